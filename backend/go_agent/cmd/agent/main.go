@@ -10,17 +10,31 @@ import (
 	"syscall"
 
 	"yogz/go_agent/internal/agent"
+	"yogz/go_agent/internal/clicmd"
 	"yogz/go_agent/internal/config"
 	"yogz/go_agent/internal/server"
 	"yogz/go_agent/internal/supervisor"
 )
 
 func main() {
+	// `coreai exec <tool> [flags] [args...]` is handled before flag.Parse,
+	// since its subcommand-style args (e.g. "exec run --timeout=10s -- ls
+	// -la") aren't shaped like the -mode/-host/-port flags below and would
+	// otherwise trip flag.Parse's "provided but not defined" error. It
+	// reuses the exact cobra command tree the interactive CLI's "/run ..."
+	// dispatcher runs through (see internal/clicmd), so scripts get the
+	// same flags and help text without a REPL.
+	if len(os.Args) > 1 && os.Args[1] == "exec" {
+		runExec(os.Args[2:])
+		return
+	}
+
 	cfg := config.Load()
 
 	mode := flag.String("mode", "cli", "cli|api")
 	host := flag.String("host", cfg.Host, "API host")
 	port := flag.Int("port", cfg.Port, "API port")
+	maxSteps := flag.Int("max-steps", 6, "max planner iterations (model call + tool dispatch) per CLI question")
 	flag.Parse()
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -37,7 +51,7 @@ func main() {
 
 	switch *mode {
 	case "cli":
-		if err := ag.RunCLI(ctx); err != nil {
+		if err := ag.RunCLI(ctx, *maxSteps); err != nil {
 			log.Fatal(err)
 		}
 	case "api":
@@ -51,3 +65,29 @@ func main() {
 		os.Exit(2)
 	}
 }
+
+// runExec boots just enough of the agent (config, Python sidecar, tool
+// registry) to run one tool non-interactively and exit -- no model calls,
+// no REPL -- so shell scripts can call `coreai exec grep TODO .` the same
+// way the interactive CLI's "/grep ..." does.
+func runExec(args []string) {
+	cfg := config.Load()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	res, err := supervisor.EnsurePythonService(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer res.Stop()
+	cfg.PythonToolsURL = res.PythonURL
+
+	ag := agent.New(cfg)
+	reg, rt := ag.NewToolEnv()
+	root := clicmd.NewRootCommand(reg, rt)
+	root.SetArgs(append([]string{"exec"}, args...))
+	if err := root.ExecuteContext(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
@@ -2,38 +2,61 @@ package agent
 
 import (
 	"bufio"
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"strings"
 	"sync"
-	"unicode"
 	"time"
+	"unicode"
 
+	"yogz/go_agent/internal/clicmd"
 	"yogz/go_agent/internal/config"
+	"yogz/go_agent/internal/llm"
 	"yogz/go_agent/internal/memory"
-	"yogz/go_agent/internal/ollama"
-	"yogz/go_agent/internal/tools"
+	"yogz/go_agent/internal/planner"
 	"yogz/go_agent/internal/tokens"
+	"yogz/go_agent/internal/tools"
 	"yogz/go_agent/internal/ui"
 )
 
 type Agent struct {
-	mu     sync.RWMutex
-	cfg    config.Config
-	ollama *ollama.Client
-	mem    *memory.Store
+	mu        sync.RWMutex
+	cfg       config.Config
+	providers *llm.Registry
+	provider  llm.Provider
+	mem       *memory.Store
+	sessions  *memory.SessionStore
 }
 
+// sessionContextTurns bounds how many of the active session's most recent
+// turns BuildPrompt folds into the model's context -- enough for real
+// multi-turn follow-ups without the prompt growing unbounded over a long
+// session.
+const sessionContextTurns = 8
+
+// New builds an Agent from cfg.Providers (see config.ProviderSpec), using
+// the first configured provider as the active backend for Ask/Generate and
+// handing that same provider to memory.Store for embeddings. A malformed
+// provider spec is a configuration error the caller should fail fast on, so
+// New panics rather than returning one -- callers construct it once at
+// startup, same as they already do for config.Load().
 func New(cfg config.Config) *Agent {
+	reg, err := llm.BuildRegistry(cfg.Providers)
+	if err != nil {
+		panic(fmt.Sprintf("agent: %v", err))
+	}
+	provider, err := reg.Default()
+	if err != nil {
+		panic(fmt.Sprintf("agent: %v", err))
+	}
 	return &Agent{
-		cfg:    cfg,
-		ollama: ollama.New(cfg.OllamaHost),
-		mem:    memory.NewStore(cfg),
+		cfg:       cfg,
+		providers: reg,
+		provider:  provider,
+		mem:       memory.NewStore(cfg, provider),
+		sessions:  memory.NewSessionStore(),
 	}
 }
 
@@ -66,12 +89,55 @@ func (a *Agent) Status() map[string]any {
 	}
 }
 
+// Models lists every model alias across all configured providers (see
+// config.ProviderSpec), for ModelTool to validate /model against instead of
+// a hardcoded slice.
+func (a *Agent) Models() []string {
+	return a.providers.Models()
+}
+
 func (a *Agent) FSSettings() (bool, []string) {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 	return a.cfg.EnableFS, append([]string(nil), a.cfg.AllowDirs...)
 }
 
+// Generate sends a raw prompt straight to the underlying model, bypassing
+// memory retrieval/storage. It's used by the tool-calling planner loop,
+// which builds its own prompt out of the tool manifest and transcript.
+//
+// It goes through GenerateStream rather than a single blocking call so a
+// model that stalls mid-response gets cut off by the Ollama adapter's idle
+// deadline instead of holding the request open indefinitely (the openai and
+// anthropic adapters have no idle deadline of their own); the chunks are still
+// accumulated into one string since the planner needs the complete turn to
+// parse as JSON.
+func (a *Agent) Generate(ctx context.Context, prompt string) (string, error) {
+	toks, err := a.GenerateStream(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for tok := range toks {
+		if tok.Error != "" {
+			return "", fmt.Errorf("ollama stream: %s", tok.Error)
+		}
+		b.WriteString(tok.Response)
+	}
+	return b.String(), nil
+}
+
+// GenerateStream sends a raw prompt to the model and returns its response
+// as a channel of tokens, for callers that want to forward chunks to the
+// user (CLI stdout, SSE) as they arrive instead of waiting for the full
+// answer.
+func (a *Agent) GenerateStream(ctx context.Context, prompt string) (<-chan llm.Token, error) {
+	a.mu.RLock()
+	cfg := a.cfg
+	a.mu.RUnlock()
+	return a.provider.GenerateStream(ctx, cfg.Model, prompt)
+}
+
 func (a *Agent) Ask(ctx context.Context, userID string, question string) (string, error) {
 	question = strings.TrimSpace(question)
 	if question == "" {
@@ -85,10 +151,11 @@ func (a *Agent) Ask(ctx context.Context, userID string, question string) (string
 	question = tokens.ClampChars(question, cfg.MaxInputChars)
 
 	retrieved := a.mem.Query(ctx, question, 4)
-	prompt := BuildPrompt(retrieved, question)
+	turns := a.sessions.LastTurns(userID, sessionContextTurns)
+	prompt := BuildPrompt(retrieved, turns, question)
 
 	start := time.Now()
-	ans, err := a.ollama.Generate(ctx, cfg.Model, prompt)
+	ans, err := a.provider.Generate(ctx, cfg.Model, prompt)
 	if err != nil {
 		return "", err
 	}
@@ -96,10 +163,60 @@ func (a *Agent) Ask(ctx context.Context, userID string, question string) (string
 	ans = tokens.ClampChars(strings.TrimSpace(ans), cfg.MaxOutputChars)
 
 	_ = a.mem.Add(ctx, memory.Entry{Text: "USER: " + question + "\nASSISTANT: " + ans, UserID: userID, Kind: "chat"})
+	a.sessions.Append(userID, cfg.Model, memory.Turn{Role: "user", Content: question, Timestamp: time.Now().UTC()})
+	a.sessions.Append(userID, cfg.Model, memory.Turn{Role: "assistant", Content: ans, Timestamp: time.Now().UTC()})
+	return ans, nil
+}
+
+// AskStream is Ask's streaming counterpart: it calls onToken with each
+// chunk as the model produces it, so the caller (CLI stdout, an SSE
+// handler) can show the answer as it's generated instead of waiting for
+// the full response. It still returns the accumulated answer, clamped and
+// stored in memory the same way Ask does.
+func (a *Agent) AskStream(ctx context.Context, userID, question string, onToken func(llm.Token)) (string, error) {
+	question = strings.TrimSpace(question)
+	if question == "" {
+		return "", fmt.Errorf("empty question")
+	}
+
+	a.mu.RLock()
+	cfg := a.cfg
+	a.mu.RUnlock()
+
+	question = tokens.ClampChars(question, cfg.MaxInputChars)
+
+	retrieved := a.mem.Query(ctx, question, 4)
+	turns := a.sessions.LastTurns(userID, sessionContextTurns)
+	prompt := BuildPrompt(retrieved, turns, question)
+
+	toks, err := a.provider.GenerateStream(ctx, cfg.Model, prompt)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for tok := range toks {
+		if tok.Error != "" {
+			return "", fmt.Errorf("ollama stream: %s", tok.Error)
+		}
+		if tok.Response != "" {
+			b.WriteString(tok.Response)
+			if onToken != nil {
+				onToken(tok)
+			}
+		}
+	}
+	ans := tokens.ClampChars(strings.TrimSpace(b.String()), cfg.MaxOutputChars)
+
+	_ = a.mem.Add(ctx, memory.Entry{Text: "USER: " + question + "\nASSISTANT: " + ans, UserID: userID, Kind: "chat"})
+	a.sessions.Append(userID, cfg.Model, memory.Turn{Role: "user", Content: question, Timestamp: time.Now().UTC()})
+	a.sessions.Append(userID, cfg.Model, memory.Turn{Role: "assistant", Content: ans, Timestamp: time.Now().UTC()})
 	return ans, nil
 }
 
-func BuildPrompt(mem []string, question string) string {
+// BuildPrompt assembles the model prompt from similarity-retrieved memory,
+// the active session's recent turns (real multi-turn context, distinct from
+// the retrieved blobs above), and the new question.
+func BuildPrompt(mem []string, turns []memory.Turn, question string) string {
 	b := strings.Builder{}
 	b.WriteString("You are a terminal-based coding assistant. Be concise and practical.\n")
 	if len(mem) > 0 {
@@ -110,44 +227,125 @@ func BuildPrompt(mem []string, question string) string {
 			b.WriteString("\n")
 		}
 	}
+	if len(turns) > 0 {
+		b.WriteString("Conversation so far:\n")
+		for _, t := range turns {
+			b.WriteString(t.Role)
+			b.WriteString(": ")
+			b.WriteString(t.Content)
+			b.WriteString("\n")
+		}
+	}
 	b.WriteString("\nUser: ")
 	b.WriteString(question)
 	b.WriteString("\nAssistant:")
 	return b.String()
 }
 
-func (a *Agent) RunCLI(ctx context.Context) error {
-	fmt.Println("AI Terminal Agent")
-	fmt.Println("Commands: /help /status /model /ls /run /py /exit")
-
+// NewToolEnv builds the tool Registry and Runtime shared by the interactive
+// CLI and the non-interactive "coreai exec" entrypoint (cmd/agent/main.go),
+// so both wire the same set of tools against the same Agent state.
+func (a *Agent) NewToolEnv() (*tools.Registry, *tools.Runtime) {
 	reg := tools.NewRegistry()
-	help := &tools.HelpTool{Reg: reg}
-	reg.Register(help)
+	reg.Register(&tools.HelpTool{Reg: reg})
 	reg.Register(&tools.StatusTool{})
 	reg.Register(&tools.ModelTool{})
 	reg.Register(&tools.LSTool{})
+	reg.Register(&tools.CatTool{})
+	reg.Register(&tools.WriteTool{})
+	reg.Register(&tools.PatchTool{})
+	reg.Register(&tools.GrepTool{})
 	reg.Register(&tools.RunTool{})
 	reg.Register(&tools.PythonTool{})
+	reg.Register(&tools.SessionTool{})
 
 	rt := &tools.Runtime{
-		GetStatus: func() map[string]any {
-			return a.Status()
-		},
-		GetModel: func() string { return a.GetModel() },
-		SetModel: func(m string) { a.SetModel(m) },
-
-		EnableFS: func() bool { ok, _ := a.FSSettings(); return ok }(),
-		AllowDirs: func() []string { _, dirs := a.FSSettings(); return dirs }(),
+		GetStatus:  func() map[string]any { return a.Status() },
+		GetModel:   func() string { return a.GetModel() },
+		SetModel:   func(m string) { a.SetModel(m) },
+		ListModels: func() []string { return a.Models() },
+
+		EnableFS:      func() bool { ok, _ := a.FSSettings(); return ok }(),
+		AllowDirs:     func() []string { _, dirs := a.FSSettings(); return dirs }(),
+		MaxWriteBytes: func() int64 { a.mu.RLock(); defer a.mu.RUnlock(); return a.cfg.MaxWriteBytes }(),
+		RunPolicy:     func() tools.RunPolicy { a.mu.RLock(); defer a.mu.RUnlock(); return tools.NewRunPolicy(a.cfg) }(),
 		PythonToolsURL: func() string {
 			a.mu.RLock()
 			defer a.mu.RUnlock()
 			return a.cfg.PythonToolsURL
 		}(),
+
+		SessionNew: func() tools.SessionInfo {
+			return toSessionInfo(a.sessions.New(cliUserID, a.GetModel()))
+		},
+		SessionList: func() []tools.SessionInfo {
+			sessions := a.sessions.List(cliUserID)
+			out := make([]tools.SessionInfo, len(sessions))
+			for i, s := range sessions {
+				out[i] = toSessionInfo(s)
+			}
+			return out
+		},
+		SessionLoad: func(id string) error { return a.sessions.SetActive(cliUserID, id) },
+		SessionSave: func(id, path string) error { return a.saveSession(id, path) },
+		SessionReplay: func(id string) (string, error) {
+			return a.sessions.Replay(id)
+		},
+		SessionFork: func() tools.SessionInfo {
+			return toSessionInfo(a.sessions.Fork(cliUserID, a.GetModel()))
+		},
 	}
+	return reg, rt
+}
+
+// cliUserID identifies the interactive CLI's session owner, matching the
+// "cli" user id PythonTool already uses for /py requests against the
+// Python tool server.
+const cliUserID = "cli"
+
+func toSessionInfo(s *memory.Session) tools.SessionInfo {
+	return tools.SessionInfo{ID: s.ID, CreatedAt: s.CreatedAt, Model: s.Model, Turns: len(s.Turns)}
+}
+
+// saveSession exports a session to path: markdown for a ".md" extension,
+// indented JSON otherwise. An empty id saves the active CLI session.
+func (a *Agent) saveSession(id, path string) error {
+	if strings.TrimSpace(id) == "" {
+		id = a.sessions.Active(cliUserID, a.GetModel()).ID
+	}
+	var data []byte
+	if strings.HasSuffix(strings.ToLower(path), ".md") {
+		md, err := a.sessions.ExportMarkdown(id)
+		if err != nil {
+			return err
+		}
+		data = []byte(md)
+	} else {
+		b, err := a.sessions.ExportJSON(id)
+		if err != nil {
+			return err
+		}
+		data = b
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+// RunCLI drives the interactive terminal loop. maxSteps bounds how many
+// planner iterations (model call + tool dispatch) a single question may
+// take before the loop gives up, guarding against a model that keeps
+// requesting tools indefinitely; 0 uses planner.Loop's own default.
+func (a *Agent) RunCLI(ctx context.Context, maxSteps int) error {
+	fmt.Println("AI Terminal Agent")
+	fmt.Println("Commands: /help /status /model /ls /cat /write /patch /grep /run /py /session /exit")
+
+	reg, rt := a.NewToolEnv()
 
 	scanner := bufio.NewScanner(os.Stdin)
 	// Avoid truncated input (default token limit is 64K) which can corrupt commands.
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	// Guards the confirmation prompt below against interleaved output if
+	// the planner ever dispatches more than one /run call in a turn.
+	var confirmMu sync.Mutex
 	for {
 		select {
 		case <-ctx.Done():
@@ -172,17 +370,22 @@ func (a *Agent) RunCLI(ctx context.Context) error {
 		}
 
 		if strings.HasPrefix(line, "/") {
-			name, args := parseCommand(line)
-			// Show badge for explicit commands
-			if name == "run" {
-				fmt.Print(ui.Badge("RUN", "green"))
-			} else if name == "ls" {
-				fmt.Print(ui.Badge("LS", "blue"))
-			} else if name == "py" {
-				fmt.Print(ui.Badge("PY", "yellow"))
+			rest := strings.TrimSpace(strings.TrimPrefix(line, "/"))
+			name, _ := parseCommand(line)
+
+			// `/help <tool>` delegates to cobra's generated --help instead
+			// of HelpTool's one-line summary, so it reflects the real
+			// --timeout/--cwd/--depth/--hidden/--venv flags below.
+			if name == "help" {
+				if fields := strings.Fields(rest); len(fields) > 1 {
+					rest = strings.Join(fields[1:], " ") + " --help"
+				}
+			} else {
+				fmt.Print(ui.Badge(strings.ToUpper(name), badgeColor(name)))
 			}
-			out, err := reg.Run(ctx, rt, name, args)
-			if err != nil {
+
+			out, err := clicmd.Dispatch(ctx, reg, rt, rest)
+			if err != nil && strings.TrimSpace(out) == "" {
 				fmt.Println(ui.Badge("ERROR", "red"), err)
 				continue
 			}
@@ -192,212 +395,84 @@ func (a *Agent) RunCLI(ctx context.Context) error {
 			continue
 		}
 
-		// Auto-route common filesystem listing questions to /ls so we don't
-		// generate shell commands in the answer.
-		if shouldAutoLS(line) {
-			fmt.Print(ui.Badge("LS", "blue"))
-			out, err := reg.Run(ctx, rt, "ls", nil)
-			if err != nil {
-				fmt.Println("error:", err)
-				continue
-			}
-			if strings.TrimSpace(out) != "" {
-				fmt.Println(out)
-			}
-			continue
+		// Any remaining natural-language input goes through the same
+		// multi-step tool-calling loop the HTTP /v1/ask endpoint uses: the
+		// model sees the tool manifest and decides for itself whether to
+		// call /ls, /run, /py, etc. -- composing them as needed instead of
+		// this CLI hard-coding a handful of "looks like a file listing
+		// question" heuristics.
+		var onToken func(string)
+		loop := &planner.Loop{
+			Registry:       reg,
+			Runtime:        rt,
+			Generate:       a.Generate,
+			GenerateStream: a.GenerateStream,
+			OnToken:        func(delta string) { onToken(delta) },
+			MaxIterations:  maxSteps,
+			CallTimeout:    20 * time.Second,
+			OnEvent: func(kind string, payload any) {
+				if kind != "tool_call" {
+					return
+				}
+				if call, ok := payload.(planner.ToolCall); ok {
+					fmt.Print(ui.Badge(strings.ToUpper(call.Name), badgeColor(call.Name)))
+				}
+			},
+			// Plan-produced (i.e. model-chosen, not user-typed) /run calls
+			// that aren't on the run-policy allow-list get an interactive
+			// y/N gate instead of running silently -- LLM output driving a
+			// shell is exactly the case run.go's RunPolicy exists for.
+			Confirm: func(ctx context.Context, call planner.ToolCall) bool {
+				if call.Name != "run" {
+					return true
+				}
+				cmdStr, _ := call.Arguments["command"].(string)
+				if tools.RunAllowed(rt.RunPolicy, cmdStr) {
+					return true
+				}
+				confirmMu.Lock()
+				defer confirmMu.Unlock()
+				fmt.Printf("\n%s model wants to run %q (not on the run-policy allow-list). Allow? [y/N] ", ui.Badge("CONFIRM", "yellow"), cmdStr)
+				if !scanner.Scan() {
+					return false
+				}
+				return strings.EqualFold(strings.TrimSpace(scanner.Text()), "y")
+			},
 		}
-
-		// Auto-route common "what files are in this directory/folder/dict" queries to /run dir
-		if shouldAutoRunDir(line) {
-			fmt.Print(ui.Badge("AUTO", "yellow"))
-			out, err := reg.Run(ctx, rt, "run", []string{"dir"})
-			if err != nil {
-				fmt.Println("error:", err)
-				continue
-			}
-			if strings.TrimSpace(out) != "" {
-				fmt.Println(out)
+		streamed := false
+		answer, err := ui.StreamingSpinner("Thinking...", func(onTok func(string)) (string, error) {
+			onToken = func(delta string) {
+				streamed = true
+				onTok(delta)
 			}
-			continue
-		}
-
-		// Fallback to Python service for any remaining natural-language queries
-		out, err := ui.Spinner("Thinking...", func() (string, error) {
-			return callPythonService(ctx, rt.PythonToolsURL, line, rt.GetModel())
+			a, _, e := loop.Run(ctx, line)
+			return a, e
 		})
-		if err == nil && out != "" {
-			// If the response looks like a JSON plan, execute it
-			if strings.HasPrefix(strings.TrimSpace(out), "{") {
-				if planOut, planErr := executeJSONPlan(ctx, rt, out); planErr == nil && planOut != "" {
-					fmt.Println(planOut)
-					continue
-				}
-			}
-			fmt.Println(out)
-			continue
-		} else if err != nil {
-			fmt.Println(ui.Badge("ERROR", "red"), err)
-		}
-
-		ans, err := a.Ask(ctx, "cli", line)
 		if err != nil {
-			fmt.Println("error:", err)
+			fmt.Println(ui.Badge("ERROR", "red"), err)
 			continue
 		}
-		fmt.Println(sanitizeForTerminal(ans))
+		if !streamed && strings.TrimSpace(answer) != "" {
+			fmt.Println(answer)
+		}
 	}
 }
 
-func executeJSONPlan(ctx context.Context, rt *tools.Runtime, jsonStr string) (string, error) {
-	var plan struct {
-		Action    string   `json:"action"`
-		Command   string   `json:"command"`
-		Arguments []string `json:"arguments"`
-	}
-	if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
-		return "", fmt.Errorf("invalid plan JSON")
-	}
-	switch plan.Action {
+// badgeColor picks the CLI badge color for a tool name, matching the
+// colors RunCLI used when these were hard-coded per command.
+func badgeColor(tool string) string {
+	switch tool {
 	case "run":
-		// Prefer command field if present, otherwise construct from arguments
-		cmd := plan.Command
-		if cmd == "" && len(plan.Arguments) > 0 {
-			cmd = strings.Join(plan.Arguments, " ")
-		}
-		if cmd == "" {
-			return "", fmt.Errorf("run plan missing command")
-		}
-		// Normalize heredoc-style commands for Windows
-		cmd = normalizeCommand(cmd)
-		runTool := &tools.RunTool{}
-		return runTool.Run(ctx, rt, strings.Fields(cmd))
+		return "green"
+	case "ls":
+		return "blue"
+	case "py":
+		return "yellow"
 	default:
-		return "", fmt.Errorf("unsupported plan action: %s", plan.Action)
+		return "gray"
 	}
 }
 
-func normalizeCommand(cmd string) string {
-	// Simple heuristic: if it looks like a Python heredoc, convert to -c
-	if strings.Contains(cmd, "<<'PY'") {
-		start := strings.Index(cmd, "<<'PY'")
-		if start == -1 {
-			return cmd
-		}
-		start += len("<<'PY'")
-		end := strings.Index(cmd[start:], "PY")
-		if end == -1 {
-			return cmd
-		}
-		code := strings.TrimSpace(cmd[start : start+end])
-		// Escape quotes and wrap in -c
-		code = strings.ReplaceAll(code, `"`, `\"`)
-		return fmt.Sprintf("python -c \"%s\"", code)
-	}
-	// Normalize python3 to python on Windows
-	if strings.HasPrefix(cmd, "python3 ") {
-		return "python " + cmd[7:]
-	}
-	return cmd
-}
-
-func callPythonService(ctx context.Context, pythonToolsURL, question, model string) (string, error) {
-	if pythonToolsURL == "" {
-		return "", fmt.Errorf("python service not configured")
-	}
-	payload := map[string]any{"question": question, "user_id": "cli", "model": model}
-	body, _ := json.Marshal(payload)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(pythonToolsURL, "/")+"/ask", bytes.NewReader(body))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return "", err
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("python service error: %d", resp.StatusCode)
-	}
-	var result struct {
-		Answer string `json:"answer"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return "", err
-	}
-	return result.Answer, nil
-}
-
-func shouldAutoRunDir(s string) bool {
-	s = strings.ToLower(strings.TrimSpace(s))
-	if s == "" {
-		return false
-	}
-	// Match variants like "what files are in this directory/folder/dict"
-	if strings.Contains(s, "what files") && (strings.Contains(s, "directory") || strings.Contains(s, "folder") || strings.Contains(s, "dict")) {
-		return true
-	}
-	if strings.Contains(s, "what are the files") && (strings.Contains(s, "directory") || strings.Contains(s, "folder") || strings.Contains(s, "dict")) {
-		return true
-	}
-	if strings.Contains(s, "files we have") && (strings.Contains(s, "directory") || strings.Contains(s, "folder") || strings.Contains(s, "dict")) {
-		return true
-	}
-	if strings.Contains(s, "files in this") && (strings.Contains(s, "directory") || strings.Contains(s, "folder") || strings.Contains(s, "dict")) {
-		return true
-	}
-	// Broader triggers: "in this dict", "show me what's here", "what's in here"
-	if strings.Contains(s, "in this dict") {
-		return true
-	}
-	if strings.Contains(s, "show me what") && (strings.Contains(s, "here") || strings.Contains(s, "this")) {
-		return true
-	}
-	if strings.Contains(s, "what") && (strings.Contains(s, "here") || strings.Contains(s, "this folder") || strings.Contains(s, "this directory")) {
-		return true
-	}
-	// New triggers: "show me files", "show files", "list files"
-	if strings.Contains(s, "show me files") || strings.Contains(s, "show files") || strings.Contains(s, "list files") {
-		return true
-	}
-	return false
-}
-
-func shouldAutoLS(s string) bool {
-	s = strings.ToLower(strings.TrimSpace(s))
-	if s == "" {
-		return false
-	}
-	// Keep this intentionally conservative; we only want to intercept
-	// clear "list files" intent.
-	if strings.Contains(s, "list files") {
-		return true
-	}
-	if strings.Contains(s, "files are in") && strings.Contains(s, "folder") {
-		return true
-	}
-	if strings.Contains(s, "files are in") && strings.Contains(s, "directory") {
-		return true
-	}
-	if strings.Contains(s, "what files") && (strings.Contains(s, "folder") || strings.Contains(s, "directory")) {
-		return true
-	}
-	if strings.Contains(s, "what are the files") && (strings.Contains(s, "folder") || strings.Contains(s, "directory")) {
-		return true
-	}
-	if strings.Contains(s, "files we have") && (strings.Contains(s, "folder") || strings.Contains(s, "directory")) {
-		return true
-	}
-	if strings.Contains(s, "files in this folder") || strings.Contains(s, "files in this directory") {
-		return true
-	}
-	if strings.HasPrefix(s, "show me the files") {
-		return true
-	}
-	return false
-}
-
 func parseCommand(line string) (string, []string) {
 	line = strings.TrimSpace(line)
 	if !strings.HasPrefix(line, "/") {
@@ -425,11 +500,18 @@ func sanitizeForTerminal(s string) string {
 	if s == "" {
 		return ""
 	}
+	return stripControlChars(s)
+}
+
+// stripControlChars drops terminal-corrupting control bytes (stray \r,
+// ANSI-like bytes) while keeping newlines/tabs, without trimming
+// surrounding whitespace -- unlike sanitizeForTerminal, it's safe to call
+// per-chunk on a token stream where leading/trailing spaces are
+// significant word boundaries.
+func stripControlChars(s string) string {
 	var b strings.Builder
 	b.Grow(len(s))
 	for _, r := range s {
-		// Keep newlines/tabs for readability but remove other control chars that
-		// can corrupt the prompt (e.g. stray \r or ANSI-like bytes).
 		if r == '\n' || r == '\t' {
 			b.WriteRune(r)
 			continue
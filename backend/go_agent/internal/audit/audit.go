@@ -1,46 +1,193 @@
+// Package audit records structured events -- HTTP requests, tool calls, and
+// auth decisions -- for security review and operational visibility. Events
+// are redacted, JSON-encoded, and fanned out to one or more sinks (file,
+// stdout, syslog, webhook); a short in-memory ring buffer backs live tail
+// queries so readers don't need to re-read whatever the file sink wrote.
 package audit
 
 import (
 	"encoding/json"
-	"os"
 	"sync"
 	"time"
+
+	"yogz/go_agent/internal/config"
 )
 
+// ringSize bounds how many recent events Since/Subscribe replay from memory.
+const ringSize = 512
+
+// Event is one audit record. Fields are flat so redaction and JSON
+// serialization stay simple; add new top-level fields rather than nesting.
+type Event struct {
+	Seq        uint64 `json:"seq"`
+	TS         string `json:"ts"`
+	Kind       string `json:"kind"`
+	RequestID  string `json:"request_id,omitempty"`
+	UserID     string `json:"user_id,omitempty"`
+	IP         string `json:"ip,omitempty"`
+	Method     string `json:"method,omitempty"`
+	Path       string `json:"path,omitempty"`
+	Status     int    `json:"status,omitempty"`
+	Tool       string `json:"tool,omitempty"`
+	Args       string `json:"args,omitempty"`
+	Result     string `json:"result,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	BytesIn    int64  `json:"bytes_in,omitempty"`
+	BytesOut   int64  `json:"bytes_out,omitempty"`
+	Note       string `json:"note,omitempty"`
+}
+
+// Sink receives redacted, JSON-encoded audit lines (no trailing newline).
+type Sink interface {
+	Write(line []byte) error
+}
+
+// Logger redacts and fans out audit events to its sinks, and keeps a short
+// in-memory ring buffer plus live subscriber channels for /v1/audit/tail.
 type Logger struct {
+	sinks    []Sink
+	redactor *Redactor
+
 	mu   sync.Mutex
-	path string
-}
+	seq  uint64
+	ring []Event
 
-type Event struct {
-	TS     string `json:"ts"`
-	Kind   string `json:"kind"`
-	UserID string `json:"user_id,omitempty"`
-	IP     string `json:"ip,omitempty"`
-	Method string `json:"method,omitempty"`
-	Path   string `json:"path,omitempty"`
-	Status int    `json:"status,omitempty"`
-	Note   string `json:"note,omitempty"`
+	subMu sync.Mutex
+	subs  map[chan Event]struct{}
 }
 
+// New builds a Logger writing to a single rotating file at path with
+// default rotation limits and no extra redaction, matching the previous
+// New(path) behavior. Use NewLogger for additional sinks or field redaction.
 func New(path string) *Logger {
-	return &Logger{path: path}
+	l, err := NewLogger(path, 0, 0, nil, "")
+	if err != nil {
+		// Preserve the old best-effort semantics: callers got a usable
+		// Logger even if the file couldn't be opened; it just drops writes.
+		return &Logger{redactor: &Redactor{}, subs: map[chan Event]struct{}{}}
+	}
+	return l
+}
+
+// NewLogger builds a Logger with a file sink at path (skipped if path is
+// empty, with rotation limits maxBytes/maxAge -- see NewFileSink) plus any
+// extra sinks, and a Redactor built from fieldPattern -- a regex matched
+// against field names, redacting that field in full on top of the
+// built-in value-pattern stripping (Authorization headers, bearer tokens,
+// API keys, email addresses); empty disables the extra field-name check.
+func NewLogger(path string, maxBytes int64, maxAge time.Duration, extra []Sink, fieldPattern string) (*Logger, error) {
+	redactor, err := NewRedactor(fieldPattern)
+	if err != nil {
+		return nil, err
+	}
+	sinks := make([]Sink, 0, len(extra)+1)
+	if path != "" {
+		fs, err := NewFileSink(path, maxBytes, maxAge)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, fs)
+	}
+	sinks = append(sinks, extra...)
+	return &Logger{
+		sinks:    sinks,
+		redactor: redactor,
+		subs:     map[chan Event]struct{}{},
+	}, nil
 }
 
+// NewFromConfig builds a Logger from the AuditXxx fields of cfg: a file
+// sink at AuditLogPath (rotation bounded by AuditMaxBytes/AuditMaxAgeHours),
+// plus syslog/webhook sinks if their settings are non-empty, and a
+// Redactor built from AuditRedactFields.
+func NewFromConfig(cfg config.Config) (*Logger, error) {
+	var extra []Sink
+	if cfg.AuditSyslogTag != "" {
+		sink, err := NewSyslogSink(cfg.AuditSyslogTag)
+		if err != nil {
+			return nil, err
+		}
+		extra = append(extra, sink)
+	}
+	if cfg.AuditWebhookURL != "" {
+		extra = append(extra, NewWebhookSink(cfg.AuditWebhookURL))
+	}
+	maxAge := time.Duration(cfg.AuditMaxAgeHours) * time.Hour
+	return NewLogger(cfg.AuditLogPath, cfg.AuditMaxBytes, maxAge, extra, cfg.AuditRedactFields)
+}
+
+// Write stamps, redacts, and records e, then hands it to every sink and
+// live subscriber. Sink errors are swallowed -- audit logging must never
+// fail the request it's describing.
 func (l *Logger) Write(e Event) {
+	e.TS = time.Now().UTC().Format(time.RFC3339)
+	e = l.redactor.apply(e)
+
 	l.mu.Lock()
-	defer l.mu.Unlock()
+	l.seq++
+	e.Seq = l.seq
+	l.ring = append(l.ring, e)
+	if len(l.ring) > ringSize {
+		l.ring = l.ring[len(l.ring)-ringSize:]
+	}
+	l.mu.Unlock()
 
-	e.TS = time.Now().UTC().Format(time.RFC3339)
 	b, err := json.Marshal(e)
 	if err != nil {
 		return
 	}
+	for _, sink := range l.sinks {
+		_ = sink.Write(b)
+	}
+	l.broadcast(e)
+}
 
-	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
-	if err != nil {
-		return
+// Since returns buffered events with Seq > since, optionally filtered to a
+// single kind ("" matches all). Only the last ringSize events are kept, so
+// a since far in the past silently returns whatever's left.
+func (l *Logger) Since(since uint64, kind string) []Event {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make([]Event, 0, len(l.ring))
+	for _, e := range l.ring {
+		if e.Seq <= since {
+			continue
+		}
+		if kind != "" && e.Kind != kind {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// Subscribe registers a channel that receives every event written after
+// this call. The channel is buffered; a subscriber too slow to keep up
+// drops events rather than blocking Write. Callers must Unsubscribe when
+// done.
+func (l *Logger) Subscribe() chan Event {
+	ch := make(chan Event, 32)
+	l.subMu.Lock()
+	l.subs[ch] = struct{}{}
+	l.subMu.Unlock()
+	return ch
+}
+
+// Unsubscribe removes and closes a channel returned by Subscribe.
+func (l *Logger) Unsubscribe(ch chan Event) {
+	l.subMu.Lock()
+	delete(l.subs, ch)
+	l.subMu.Unlock()
+	close(ch)
+}
+
+func (l *Logger) broadcast(e Event) {
+	l.subMu.Lock()
+	defer l.subMu.Unlock()
+	for ch := range l.subs {
+		select {
+		case ch <- e:
+		default:
+		}
 	}
-	defer f.Close()
-	_, _ = f.Write(append(b, '\n'))
 }
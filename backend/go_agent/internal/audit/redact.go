@@ -0,0 +1,123 @@
+package audit
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// valuePatterns are always-on value-level redactions applied to every
+// free-text Event field (Note, Args, Result) regardless of how
+// fieldPattern is configured -- secrets shaped like these (a pasted
+// Authorization header, a bearer token, an API key, an email address)
+// should never reach a sink in the clear. Each pattern with a capture
+// group keeps that group and redacts only the value after it; patterns
+// with no group are replaced outright.
+var valuePatterns = []*regexp.Regexp{
+	// "Authorization: <value>" however it ends up embedded in free text,
+	// e.g. a pasted curl command or proxied header.
+	// A leading auth scheme word (Bearer/Basic/HMAC) is consumed together
+	// with the credential after it, so the credential itself is redacted.
+	regexp.MustCompile(`(?i)(authorization:\s*)(?:(?:bearer|basic|hmac)\s+)?\S+`),
+	// Bearer tokens outside of an Authorization header, e.g. one pasted
+	// straight into a /run command line or a tool's raw output.
+	regexp.MustCompile(`(?i)\bbearer\s+\S+`),
+	// API-key-shaped tokens: a recognizable prefix plus a long opaque
+	// suffix, the shape most provider keys (OpenAI, Anthropic, ...) share.
+	regexp.MustCompile(`\b(?:sk|key|api)-[A-Za-z0-9_\-]{16,}\b`),
+	// Email addresses.
+	regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`),
+}
+
+const redacted = "[REDACTED]"
+
+// redactValues replaces every valuePatterns match found in s with redacted.
+func redactValues(s string) string {
+	for _, re := range valuePatterns {
+		if re.NumSubexp() > 0 {
+			s = re.ReplaceAllString(s, "${1}"+redacted)
+		} else {
+			s = re.ReplaceAllString(s, redacted)
+		}
+	}
+	return s
+}
+
+// Redactor strips sensitive values from an Event before it's serialized.
+// It always strips valuePatterns matches from every free-text field;
+// fieldPattern additionally marks whichever Event fields it matches by
+// name for full redaction, covering fields a deployer knows carry
+// sensitive data that valuePatterns wouldn't recognize on its own.
+type Redactor struct {
+	redactUserID    bool
+	redactIP        bool
+	redactRequestID bool
+	redactPath      bool
+	redactMethod    bool
+	redactTool      bool
+	redactArgs      bool
+	redactResult    bool
+	redactNote      bool
+}
+
+// NewRedactor compiles fieldPattern and matches it against every Event
+// field name (its lowercase json tag: "user_id", "ip", "request_id",
+// "path", "method", "tool", "args", "result", "note") to decide which
+// fields get fully redacted on top of the built-in value-pattern
+// stripping. An empty pattern disables field-name redaction.
+func NewRedactor(fieldPattern string) (*Redactor, error) {
+	if fieldPattern == "" {
+		return &Redactor{}, nil
+	}
+	re, err := regexp.Compile(fieldPattern)
+	if err != nil {
+		return nil, fmt.Errorf("compile audit redaction pattern %q: %w", fieldPattern, err)
+	}
+	return &Redactor{
+		redactUserID:    re.MatchString("user_id"),
+		redactIP:        re.MatchString("ip"),
+		redactRequestID: re.MatchString("request_id"),
+		redactPath:      re.MatchString("path"),
+		redactMethod:    re.MatchString("method"),
+		redactTool:      re.MatchString("tool"),
+		redactArgs:      re.MatchString("args"),
+		redactResult:    re.MatchString("result"),
+		redactNote:      re.MatchString("note"),
+	}, nil
+}
+
+func (r *Redactor) apply(e Event) Event {
+	e.Note = redactValues(e.Note)
+	e.Args = redactValues(e.Args)
+	e.Result = redactValues(e.Result)
+	if r == nil {
+		return e
+	}
+	if r.redactUserID {
+		e.UserID = redacted
+	}
+	if r.redactIP {
+		e.IP = redacted
+	}
+	if r.redactRequestID {
+		e.RequestID = redacted
+	}
+	if r.redactPath {
+		e.Path = redacted
+	}
+	if r.redactMethod {
+		e.Method = redacted
+	}
+	if r.redactTool {
+		e.Tool = redacted
+	}
+	if r.redactArgs {
+		e.Args = redacted
+	}
+	if r.redactResult {
+		e.Result = redacted
+	}
+	if r.redactNote {
+		e.Note = redacted
+	}
+	return e
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxBytes = 10 * 1024 * 1024
+	defaultMaxAge   = 7 * 24 * time.Hour
+)
+
+// FileSink writes audit lines to a single file, keeping the handle open
+// across writes. It rotates by size and age, gzipping each rotated
+// segment in the background so a burst of events never blocks on disk I/O
+// for the old file.
+type FileSink struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxAge   time.Duration
+	f        *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// NewFileSink opens (creating if needed) a rotating file sink at path.
+// maxBytes <= 0 defaults to 10 MiB; maxAge <= 0 defaults to 7 days.
+func NewFileSink(path string, maxBytes int64, maxAge time.Duration) (*FileSink, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	if maxAge <= 0 {
+		maxAge = defaultMaxAge
+	}
+	s := &FileSink{path: path, maxBytes: maxBytes, maxAge: maxAge}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.size = info.Size()
+	s.openedAt = time.Now()
+	return nil
+}
+
+func (s *FileSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		if err := s.open(); err != nil {
+			return err
+		}
+	}
+	if s.size > 0 && (s.size+int64(len(line))+1 > s.maxBytes || time.Since(s.openedAt) > s.maxAge) {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.f.Write(append(line, '\n'))
+	s.size += int64(n)
+	return err
+}
+
+func (s *FileSink) rotate() error {
+	_ = s.f.Close()
+	s.f = nil
+	rotated := fmt.Sprintf("%s.%s", s.path, time.Now().UTC().Format("20060102T150405"))
+	if err := os.Rename(s.path, rotated); err != nil {
+		// Rotation failed (e.g. path already gone); keep logging to the
+		// original path rather than dropping events.
+		return s.open()
+	}
+	go gzipAndRemove(rotated)
+	return s.open()
+}
+
+func gzipAndRemove(path string) {
+	in, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer in.Close()
+	out, err := os.Create(path + ".gz")
+	if err != nil {
+		return
+	}
+	defer out.Close()
+	gw := gzip.NewWriter(out)
+	if _, err := io.Copy(gw, in); err != nil {
+		gw.Close()
+		return
+	}
+	if err := gw.Close(); err != nil {
+		return
+	}
+	_ = os.Remove(path)
+}
@@ -0,0 +1,19 @@
+package audit
+
+import (
+	"os"
+	"sync"
+)
+
+// StdoutSink writes audit lines to the process's standard output, useful
+// when a container runtime is already collecting stdout as the log sink.
+type StdoutSink struct {
+	mu sync.Mutex
+}
+
+func (s *StdoutSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := os.Stdout.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,22 @@
+package audit
+
+import "log/syslog"
+
+// SyslogSink forwards audit lines to the local syslog daemon under tag.
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink dials the local syslog daemon. tag identifies this process
+// in syslog output (e.g. "go_agent").
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_DAEMON, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Write(line []byte) error {
+	return s.w.Info(string(line))
+}
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookSink POSTs each audit line as the JSON body of an HTTP request,
+// for forwarding into external monitoring or SIEM pipelines.
+type WebhookSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookSink(url string) *WebhookSink {
+	return &WebhookSink{url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (s *WebhookSink) Write(line []byte) error {
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(line))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("audit webhook %s returned %s", s.url, resp.Status)
+	}
+	return nil
+}
@@ -1,47 +1,168 @@
 package auth
 
 import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
 	"strings"
-
-	"yogz/go_agent/internal/config"
+	"time"
 )
 
-func Middleware(cfg config.Config, next http.Handler) http.Handler {
-	// If no API keys configured, allow all.
-	if len(cfg.APIKeys) == 0 {
+const hmacSkew = 5 * time.Minute
+
+type contextKey int
+
+const keyContextKey contextKey = 0
+
+// Middleware enforces that the request carries a valid, non-expired API key
+// with the given scope (pass "" to only require authentication). It accepts
+// either a bearer token ("Authorization: Bearer <id>.<secret>") or an
+// HMAC-signed request ("Authorization: HMAC keyid=...,signature=...,ts=...").
+// If store is nil (no keys configured), every request is allowed, matching
+// the previous open-by-default behavior for local/dev use. The verified key
+// is attached to the request context so handlers that need a finer-grained,
+// per-action scope (e.g. a specific tool) can check it with KeyFromContext.
+func Middleware(store KeyStore, scope string, next http.Handler) http.Handler {
+	if store == nil {
 		return next
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		tok := extractBearer(r.Header.Get("Authorization"))
-		if tok == "" {
+		key, err := authenticate(store, r)
+		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
-			_, _ = w.Write([]byte("missing api key"))
+			_, _ = w.Write([]byte(err.Error()))
 			return
 		}
-		for _, k := range cfg.APIKeys {
-			if tok == k {
-				next.ServeHTTP(w, r)
-				return
-			}
+		if scope != "" && !key.HasScope(scope) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte("key lacks required scope: " + scope))
+			return
 		}
-		w.WriteHeader(http.StatusUnauthorized)
-		_, _ = w.Write([]byte("invalid api key"))
+		r = r.WithContext(context.WithValue(r.Context(), keyContextKey, key))
+		next.ServeHTTP(w, r)
 	})
 }
 
-func extractBearer(v string) string {
-	v = strings.TrimSpace(v)
-	if v == "" {
-		return ""
+// KeyFromContext returns the API key Middleware verified for this request,
+// if any. It's false when no store is configured (open access) or the route
+// isn't behind Middleware.
+func KeyFromContext(ctx context.Context) (Key, bool) {
+	k, ok := ctx.Value(keyContextKey).(Key)
+	return k, ok
+}
+
+func authenticate(store KeyStore, r *http.Request) (Key, error) {
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	if header == "" {
+		return Key{}, errors.New("missing Authorization header")
 	}
-	if strings.HasPrefix(strings.ToLower(v), "bearer ") {
-		return strings.TrimSpace(v[7:])
+	switch {
+	case hasScheme(header, "bearer"):
+		return authenticateBearer(store, strings.TrimSpace(header[len("bearer "):]))
+	case hasScheme(header, "hmac"):
+		return authenticateHMAC(store, r, strings.TrimSpace(header[len("hmac "):]))
+	default:
+		return Key{}, errors.New("unrecognized Authorization scheme")
 	}
-	return ""
 }
 
+func hasScheme(header, scheme string) bool {
+	return len(header) > len(scheme)+1 && strings.EqualFold(header[:len(scheme)+1], scheme+" ")
+}
+
+func authenticateBearer(store KeyStore, token string) (Key, error) {
+	id, secret, ok := bearerParts(token)
+	if !ok {
+		return Key{}, errors.New("malformed bearer token (expected <id>.<secret>)")
+	}
+	key, ok := store.Lookup(id)
+	if !ok || key.Expired() || !VerifySecret(key, secret) {
+		return Key{}, errors.New("invalid api key")
+	}
+	return key, nil
+}
+
+func bearerParts(token string) (id, secret string, ok bool) {
+	i := strings.IndexByte(token, '.')
+	if i <= 0 || i == len(token)-1 {
+		return "", "", false
+	}
+	return token[:i], token[i+1:], true
+}
+
+func authenticateHMAC(store KeyStore, r *http.Request, params string) (Key, error) {
+	fields := parseHMACParams(params)
+	id, sig, tsRaw := fields["keyid"], fields["signature"], fields["ts"]
+	if id == "" || sig == "" || tsRaw == "" {
+		return Key{}, errors.New("malformed HMAC auth params")
+	}
+	key, ok := store.Lookup(id)
+	if !ok || key.Expired() || key.HMACSecret == "" {
+		return Key{}, errors.New("invalid api key")
+	}
+	ts, err := strconv.ParseInt(tsRaw, 10, 64)
+	if err != nil {
+		return Key{}, errors.New("invalid ts")
+	}
+	skew := time.Since(time.Unix(ts, 0))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > hmacSkew {
+		return Key{}, errors.New("timestamp outside allowed skew")
+	}
+
+	var body []byte
+	if r.Body != nil {
+		body, _ = io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	mac := hmac.New(sha256.New, []byte(key.HMACSecret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%s", r.Method, r.URL.Path, body, tsRaw)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(sig)) != 1 {
+		return Key{}, errors.New("invalid signature")
+	}
+	return key, nil
+}
+
+func parseHMACParams(s string) map[string]string {
+	out := map[string]string{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		k, v, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+		out[strings.ToLower(strings.TrimSpace(k))] = strings.Trim(strings.TrimSpace(v), `"`)
+	}
+	return out
+}
+
+// UserIDFromRequest returns the claimed key ID from the Authorization
+// header -- the bearer token's id segment, or the HMAC keyid -- without
+// re-verifying the secret. It's used for audit/rate-limit labeling so logs
+// carry an identity instead of leaking the raw credential; callers that
+// need a verified identity rely on Middleware having already rejected
+// unauthenticated requests upstream.
 func UserIDFromRequest(r *http.Request) string {
-	// Minimal: treat api key as identity.
-	return extractBearer(r.Header.Get("Authorization"))
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	switch {
+	case hasScheme(header, "bearer"):
+		id, _, ok := bearerParts(strings.TrimSpace(header[len("bearer "):]))
+		if ok {
+			return id
+		}
+	case hasScheme(header, "hmac"):
+		return parseHMACParams(strings.TrimSpace(header[len("hmac "):]))["keyid"]
+	}
+	return ""
 }
@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHasScopeWildcardAndExact(t *testing.T) {
+	k := Key{Scopes: map[string]bool{"ask": true}}
+	if !k.HasScope("ask") {
+		t.Fatalf("HasScope(ask) = false, want true")
+	}
+	if k.HasScope("tools:run") {
+		t.Fatalf("HasScope(tools:run) = true, want false (not granted)")
+	}
+
+	wildcard := Key{Scopes: map[string]bool{"*": true}}
+	if !wildcard.HasScope("tools:run") {
+		t.Fatalf("HasScope on a \"*\" key = false, want true")
+	}
+}
+
+func TestHasScopeNilScopesDeniesEverything(t *testing.T) {
+	var k Key
+	if k.HasScope("ask") {
+		t.Fatalf("zero-value Key.HasScope = true, want false")
+	}
+}
+
+func TestAuthenticateBearerRequiresMatchingSecret(t *testing.T) {
+	hash, err := HashSecret("correct-horse")
+	if err != nil {
+		t.Fatalf("HashSecret: %v", err)
+	}
+	store := NewMemoryKeyStore(Key{ID: "k1", Hash: hash, ScopeList: []string{"ask"}})
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/ask", nil)
+	req.Header.Set("Authorization", "Bearer k1.correct-horse")
+	if _, err := authenticate(store, req); err != nil {
+		t.Fatalf("authenticate with correct secret: %v", err)
+	}
+
+	bad := httptest.NewRequest(http.MethodPost, "/v1/ask", nil)
+	bad.Header.Set("Authorization", "Bearer k1.wrong-secret")
+	if _, err := authenticate(store, bad); err == nil {
+		t.Fatalf("authenticate with wrong secret: want error, got nil")
+	}
+}
+
+func TestAuthenticateBearerRejectsExpiredKey(t *testing.T) {
+	hash, _ := HashSecret("s")
+	store := NewMemoryKeyStore(Key{
+		ID: "k1", Hash: hash, ScopeList: []string{"*"},
+		ExpiresAt: time.Now().Add(-time.Hour),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/ask", nil)
+	req.Header.Set("Authorization", "Bearer k1.s")
+	if _, err := authenticate(store, req); err == nil {
+		t.Fatalf("authenticate with expired key: want error, got nil")
+	}
+}
+
+func signHMAC(secret, method, path string, body []byte, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s\n%s\n%s\n%d", method, path, body, ts)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestAuthenticateHMACValidSignature(t *testing.T) {
+	store := NewMemoryKeyStore(Key{ID: "k1", HMACSecret: "shared-secret", ScopeList: []string{"*"}})
+	ts := time.Now().Unix()
+	body := []byte(`{"question":"hi"}`)
+	sig := signHMAC("shared-secret", http.MethodPost, "/v1/ask", body, ts)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/ask", strings.NewReader(string(body)))
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC keyid=k1,signature=%s,ts=%d", sig, ts))
+	if _, err := authenticate(store, req); err != nil {
+		t.Fatalf("authenticate with valid HMAC signature: %v", err)
+	}
+}
+
+func TestAuthenticateHMACRejectsTamperedBody(t *testing.T) {
+	store := NewMemoryKeyStore(Key{ID: "k1", HMACSecret: "shared-secret", ScopeList: []string{"*"}})
+	ts := time.Now().Unix()
+	sig := signHMAC("shared-secret", http.MethodPost, "/v1/ask", []byte(`{"question":"hi"}`), ts)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/ask", strings.NewReader(`{"question":"tampered"}`))
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC keyid=k1,signature=%s,ts=%d", sig, ts))
+	if _, err := authenticate(store, req); err == nil {
+		t.Fatalf("authenticate with tampered body: want error, got nil")
+	}
+}
+
+func TestAuthenticateHMACRejectsStaleTimestamp(t *testing.T) {
+	store := NewMemoryKeyStore(Key{ID: "k1", HMACSecret: "shared-secret", ScopeList: []string{"*"}})
+	ts := time.Now().Add(-hmacSkew - time.Minute).Unix()
+	body := []byte(`{}`)
+	sig := signHMAC("shared-secret", http.MethodPost, "/v1/ask", body, ts)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/ask", strings.NewReader(string(body)))
+	req.Header.Set("Authorization", fmt.Sprintf("HMAC keyid=k1,signature=%s,ts=%d", sig, ts))
+	if _, err := authenticate(store, req); err == nil {
+		t.Fatalf("authenticate with timestamp outside skew: want error, got nil")
+	}
+}
+
+func TestMiddlewareEnforcesScope(t *testing.T) {
+	hash, _ := HashSecret("s")
+	store := NewMemoryKeyStore(Key{ID: "k1", Hash: hash, ScopeList: []string{"ask"}})
+	handler := Middleware(store, "tools:run", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/tools/run", nil)
+	req.Header.Set("Authorization", "Bearer k1.s")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403 (key lacks tools:run scope)", rec.Code)
+	}
+}
+
+func TestMiddlewareOpenAccessWithNoStore(t *testing.T) {
+	handler := Middleware(nil, "tools:run", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	req := httptest.NewRequest(http.MethodGet, "/v1/tools/run", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200 (nil store means open access)", rec.Code)
+	}
+}
+
+func TestUserIDFromRequestBearerAndHMAC(t *testing.T) {
+	bearer := httptest.NewRequest(http.MethodGet, "/", nil)
+	bearer.Header.Set("Authorization", "Bearer abc123.secret")
+	if got := UserIDFromRequest(bearer); got != "abc123" {
+		t.Fatalf("UserIDFromRequest(bearer) = %q, want %q", got, "abc123")
+	}
+
+	hmacReq := httptest.NewRequest(http.MethodGet, "/", nil)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	hmacReq.Header.Set("Authorization", "HMAC keyid=xyz,signature=deadbeef,ts="+ts)
+	if got := UserIDFromRequest(hmacReq); got != "xyz" {
+		t.Fatalf("UserIDFromRequest(hmac) = %q, want %q", got, "xyz")
+	}
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Key is one API credential: an opaque ID safe to log, a bcrypt hash of its
+// bearer secret, the scopes it may use, and optional expiry / per-key
+// rate-limit override. HMACSecret, when set, is the raw shared secret used
+// to verify HMAC-signed requests for this key -- that mode inherently needs
+// the plaintext server-side, unlike the bearer secret which only needs a
+// hash to verify against.
+type Key struct {
+	ID              string          `json:"id"`
+	Hash            []byte          `json:"hash"`
+	HMACSecret      string          `json:"hmac_secret,omitempty"`
+	Scopes          map[string]bool `json:"-"`
+	ScopeList       []string        `json:"scopes"`
+	ExpiresAt       time.Time       `json:"expires_at,omitempty"`
+	RateLimitPerMin int             `json:"rate_limit_per_min,omitempty"`
+}
+
+func (k Key) Expired() bool {
+	return !k.ExpiresAt.IsZero() && time.Now().After(k.ExpiresAt)
+}
+
+func (k Key) HasScope(scope string) bool {
+	if k.Scopes == nil {
+		return false
+	}
+	return k.Scopes["*"] || k.Scopes[scope]
+}
+
+func scopeSet(list []string) map[string]bool {
+	m := make(map[string]bool, len(list))
+	for _, s := range list {
+		m[strings.TrimSpace(s)] = true
+	}
+	return m
+}
+
+// HashSecret bcrypt-hashes a bearer secret for storage in a Key record.
+func HashSecret(secret string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+}
+
+// VerifySecret checks a presented bearer secret against the key's stored hash.
+func VerifySecret(k Key, secret string) bool {
+	if len(k.Hash) == 0 {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword(k.Hash, []byte(secret)) == nil
+}
+
+// KeyStore resolves an API key ID to its record. Implementations must be
+// safe for concurrent use.
+type KeyStore interface {
+	Lookup(id string) (Key, bool)
+}
+
+// MemoryKeyStore is a KeyStore backed by an in-process map.
+type MemoryKeyStore struct {
+	mu   sync.RWMutex
+	keys map[string]Key
+}
+
+func NewMemoryKeyStore(keys ...Key) *MemoryKeyStore {
+	m := &MemoryKeyStore{keys: map[string]Key{}}
+	for _, k := range keys {
+		m.Put(k)
+	}
+	return m
+}
+
+func (m *MemoryKeyStore) Lookup(id string) (Key, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	k, ok := m.keys[id]
+	return k, ok
+}
+
+func (m *MemoryKeyStore) Put(k Key) {
+	if k.Scopes == nil {
+		k.Scopes = scopeSet(k.ScopeList)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keys[k.ID] = k
+}
+
+// ParseKeySpecs builds a MemoryKeyStore from AGENT_API_KEYS-style entries of
+// the form "id:secret[:scope1|scope2[:rate_limit_per_min]]". A bare
+// "id:secret" grants every scope ("*"). Secrets are bcrypt-hashed on load;
+// they're never retained in plaintext.
+func ParseKeySpecs(specs []string) (*MemoryKeyStore, error) {
+	store := NewMemoryKeyStore()
+	for _, spec := range specs {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("invalid api key spec %q (want id:secret[:scopes[:rate_limit]])", spec)
+		}
+		id, secret := strings.TrimSpace(parts[0]), parts[1]
+		hash, err := HashSecret(secret)
+		if err != nil {
+			return nil, fmt.Errorf("hash secret for key %q: %w", id, err)
+		}
+		scopes := []string{"*"}
+		if len(parts) >= 3 && strings.TrimSpace(parts[2]) != "" {
+			scopes = strings.Split(parts[2], "|")
+		}
+		rateLimit := 0
+		if len(parts) >= 4 {
+			fmt.Sscanf(parts[3], "%d", &rateLimit)
+		}
+		store.Put(Key{ID: id, Hash: hash, ScopeList: scopes, RateLimitPerMin: rateLimit})
+	}
+	if len(store.keys) == 0 {
+		return nil, nil
+	}
+	return store, nil
+}
+
+// FileKeyStore loads key records from a JSON file and reloads them
+// whenever the file's mtime changes, so rotating or revoking a key doesn't
+// require a process restart.
+type FileKeyStore struct {
+	path string
+
+	mu      sync.RWMutex
+	keys    map[string]Key
+	modTime time.Time
+}
+
+func NewFileKeyStore(path string) (*FileKeyStore, error) {
+	f := &FileKeyStore{path: path}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileKeyStore) reload() error {
+	st, err := os.Stat(f.path)
+	if err != nil {
+		return err
+	}
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	var records []Key
+	if err := json.Unmarshal(b, &records); err != nil {
+		return fmt.Errorf("parse key store %s: %w", f.path, err)
+	}
+	keys := make(map[string]Key, len(records))
+	for _, k := range records {
+		k.Scopes = scopeSet(k.ScopeList)
+		keys[k.ID] = k
+	}
+	f.mu.Lock()
+	f.keys = keys
+	f.modTime = st.ModTime()
+	f.mu.Unlock()
+	return nil
+}
+
+func (f *FileKeyStore) Lookup(id string) (Key, bool) {
+	if st, err := os.Stat(f.path); err == nil {
+		f.mu.RLock()
+		stale := st.ModTime().After(f.modTime)
+		f.mu.RUnlock()
+		if stale {
+			_ = f.reload()
+		}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	k, ok := f.keys[id]
+	return k, ok
+}
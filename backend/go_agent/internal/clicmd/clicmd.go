@@ -0,0 +1,186 @@
+// Package clicmd builds the cobra command tree shared by the interactive
+// CLI dispatcher (agent.RunCLI) and the non-interactive "coreai exec"
+// entrypoint (cmd/agent/main.go). Both used to parse commands with
+// agent.parseCommand's naive strings.Fields split; routing them through the
+// same cobra.Command tree instead gives real shell-quoted argument parsing,
+// typed per-tool flags, and generated --help text for free.
+package clicmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"yogz/go_agent/internal/tools"
+)
+
+// NewRootCommand builds the "coreai" command tree: an "exec" subcommand
+// with one child per registered tool, so `coreai exec run -- ls -la` and
+// the interactive "/run ..." dispatcher resolve through the exact same
+// cobra.Command definitions.
+func NewRootCommand(reg *tools.Registry, rt *tools.Runtime) *cobra.Command {
+	root := &cobra.Command{
+		Use:           "coreai",
+		Short:         "CoreAI agent command-line interface",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	exec := &cobra.Command{
+		Use:           "exec <tool> [flags] [args...]",
+		Short:         "Run a registered tool non-interactively",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+	}
+	for _, name := range reg.Names() {
+		exec.AddCommand(newToolCommand(reg, rt, name))
+	}
+	root.AddCommand(exec)
+	return root
+}
+
+// newToolCommand wraps a single registered tool in a *cobra.Command. run,
+// ls and py additionally get the typed pflags the backlog called out
+// (--timeout/--cwd, --depth/--hidden, --venv); every other tool just
+// forwards its positional args to Registry.Run unchanged.
+func newToolCommand(reg *tools.Registry, rt *tools.Runtime, name string) *cobra.Command {
+	t, _ := reg.Get(name)
+	cmd := &cobra.Command{
+		Use:                   name + " [args...]",
+		Short:                 t.Help(),
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		SilenceErrors:         true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAndPrint(cmd, reg, rt, name, cmd.Context(), args)
+		},
+	}
+	switch name {
+	case "run":
+		addRunFlags(cmd, reg, rt, name)
+	case "ls":
+		addLSFlags(cmd, reg, rt, name)
+	case "py":
+		addPyFlags(cmd, reg, rt, name)
+	}
+	return cmd
+}
+
+func addRunFlags(cmd *cobra.Command, reg *tools.Registry, rt *tools.Runtime, name string) {
+	var timeout time.Duration
+	var cwd string
+	var dryRun bool
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "kill the command if it runs longer than this (e.g. 10s)")
+	cmd.Flags().StringVar(&cwd, "cwd", "", "working directory to run the command in")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "print the command as the platform's CommandShim chain would translate it, without executing it")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := tools.WithRunOptions(cmd.Context(), tools.RunOptions{Timeout: timeout, Cwd: cwd, DryRun: dryRun})
+		return runAndPrint(cmd, reg, rt, name, ctx, args)
+	}
+}
+
+func addLSFlags(cmd *cobra.Command, reg *tools.Registry, rt *tools.Runtime, name string) {
+	var depth int
+	var hidden bool
+	cmd.Flags().IntVar(&depth, "depth", 1, "recurse this many levels deep")
+	cmd.Flags().BoolVar(&hidden, "hidden", false, "include dotfiles")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := tools.WithLSOptions(cmd.Context(), tools.LSOptions{Depth: depth, Hidden: hidden})
+		return runAndPrint(cmd, reg, rt, name, ctx, args)
+	}
+}
+
+func addPyFlags(cmd *cobra.Command, reg *tools.Registry, rt *tools.Runtime, name string) {
+	var venv bool
+	cmd.Flags().BoolVar(&venv, "venv", false, "run the question through the Python service's venv-isolated entry point")
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		ctx := tools.WithPyOptions(cmd.Context(), tools.PyOptions{Venv: venv})
+		return runAndPrint(cmd, reg, rt, name, ctx, args)
+	}
+}
+
+func runAndPrint(cmd *cobra.Command, reg *tools.Registry, rt *tools.Runtime, name string, ctx context.Context, args []string) error {
+	out, err := reg.Run(ctx, rt, name, args)
+	if strings.TrimSpace(out) != "" {
+		fmt.Fprintln(cmd.OutOrStdout(), out)
+	}
+	return err
+}
+
+// Dispatch parses a REPL line ("run --timeout=10s -- ls -la", with the
+// leading "/" already stripped) through the shared command tree and returns
+// its combined output. A fresh tree is built per call since cobra flags are
+// stateful and the tools they wrap are cheap singletons to rewire.
+func Dispatch(ctx context.Context, reg *tools.Registry, rt *tools.Runtime, line string) (string, error) {
+	fields, err := splitShellWords(line)
+	if err != nil {
+		return "", err
+	}
+	if len(fields) == 0 {
+		return "", nil
+	}
+	root := NewRootCommand(reg, rt)
+	var buf bytes.Buffer
+	root.SetOut(&buf)
+	root.SetErr(&buf)
+	root.SetArgs(append([]string{"exec"}, fields...))
+	err = root.ExecuteContext(ctx)
+	out := strings.TrimSpace(buf.String())
+	if err != nil && out == "" {
+		out = err.Error()
+	}
+	return out, err
+}
+
+// splitShellWords tokenizes a command line the way a shell would, honoring
+// single/double quotes and backslash escapes, so arguments containing
+// spaces (e.g. `/run --cwd="./sub dir" ls`) survive intact -- unlike
+// parseCommand's plain strings.Fields split it replaces.
+func splitShellWords(line string) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	haveField := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			haveField = true
+		case r == '\'' || r == '"':
+			quote = r
+			haveField = true
+		case r == ' ' || r == '\t':
+			if haveField {
+				fields = append(fields, cur.String())
+				cur.Reset()
+				haveField = false
+			}
+		default:
+			cur.WriteRune(r)
+			haveField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %q quote", string(quote))
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if haveField {
+		fields = append(fields, cur.String())
+	}
+	return fields, nil
+}
@@ -4,16 +4,17 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type Config struct {
 	Host string
 	Port int
 
-	OllamaHost string
-	Model      string
-	EmbedModel string
-	PythonToolsURL string
+	OllamaHost       string
+	Model            string
+	EmbedModel       string
+	PythonToolsURL   string
 	PythonAgentEntry string
 
 	MaxInputChars  int
@@ -21,12 +22,85 @@ type Config struct {
 
 	RateLimitPerMin int
 
-	APIKeys []string
+	// APIKeys holds AGENT_API_KEYS entries of the form
+	// "id:secret[:scope1|scope2[:rate_limit_per_min]]", parsed by
+	// auth.ParseKeySpecs. AuthKeysFile, if set, points at a JSON file of
+	// auth.Key records instead and takes precedence.
+	APIKeys      []string
+	AuthKeysFile string
 
-	EnableFS bool
+	EnableFS  bool
 	AllowDirs []string
 
-	AuditLogPath string
+	// MaxWriteBytes bounds the size of content WriteTool/PatchTool will
+	// write in a single call, so a runaway prompt can't fill the sandbox.
+	MaxWriteBytes int64
+
+	// RunAllowExec/RunDenyExec restrict which executables RunTool will
+	// invoke, matched against the first token of the command line after
+	// exec.LookPath resolution. An empty RunAllowExec allows anything not
+	// on RunDenyExec; RunDenyExec always wins. RunTimeout bounds wall-clock
+	// execution independent of the caller's context (default 30s).
+	// RunMaxOutputBytes caps combined stdout/stderr (default 256KiB).
+	// RunAllowEnv whitelists environment variables passed to the child
+	// instead of forwarding os.Environ() wholesale; RunConfineCwd, if set,
+	// requires the command's working directory to resolve within
+	// AllowDirs.
+	RunAllowExec      []string
+	RunDenyExec       []string
+	RunTimeout        time.Duration
+	RunMaxOutputBytes int
+	RunAllowEnv       []string
+	RunConfineCwd     bool
+
+	// RunLimitCPUSeconds/RunLimitAddressSpaceMB/RunLimitNoFile, if > 0,
+	// apply `ulimit -t/-v/-n` to RunTool's command on non-Windows targets
+	// before exec'ing it, bounding a runaway command's CPU time, address
+	// space and open file descriptors.
+	RunLimitCPUSeconds     int
+	RunLimitAddressSpaceMB int
+	RunLimitNoFile         int
+
+	// Providers holds the parsed AGENT_PROVIDERS entries, one per llm
+	// backend, consumed by llm.BuildRegistry. When unset, Load falls back to
+	// a single "ollama" provider built from OllamaHost/Model/EmbedModel so
+	// existing single-backend deployments keep working unconfigured.
+	Providers []ProviderSpec
+
+	// HNSWM/HNSWEfConstruction/HNSWEfSearch tune memory.Store's ANN index:
+	// M bounds neighbors per graph node, EfConstruction the candidate list
+	// size while building, EfSearch the candidate list size while querying
+	// (higher = more accurate, slower). Defaults: 16/200/64.
+	HNSWM              int
+	HNSWEfConstruction int
+	HNSWEfSearch       int
+
+	// AuditMaxBytes/AuditMaxAgeHours bound the file sink's rotation
+	// (defaults: 10 MiB, 168h); AuditRedactFields is a regex matched
+	// against event field names to redact those fields in full, on top of
+	// the built-in value-pattern stripping (Authorization headers, bearer
+	// tokens, API keys, email addresses) applied to every free-text field
+	// regardless of this setting. AuditSyslogTag/AuditWebhookURL, if set,
+	// add those sinks alongside the file sink.
+	AuditLogPath      string
+	AuditMaxBytes     int64
+	AuditMaxAgeHours  int
+	AuditRedactFields string
+	AuditSyslogTag    string
+	AuditWebhookURL   string
+}
+
+// ProviderSpec configures one llm.Provider. Scheme selects the adapter
+// ("ollama", "openai", "anthropic", "llamacpp"; llamacpp is served by the
+// openai adapter since llama.cpp's server speaks that wire format).
+// Parsed from AGENT_PROVIDERS entries of the form
+// "scheme|baseurl|apikey|model1,model2", separated by ";" across entries --
+// "|" rather than ":" so model aliases like "llama3.1:8b" parse unambiguously.
+type ProviderSpec struct {
+	Scheme  string
+	BaseURL string
+	APIKey  string
+	Models  []string
 }
 
 func Load() Config {
@@ -46,14 +120,44 @@ func Load() Config {
 	cfg.RateLimitPerMin = getenvInt("AGENT_RATE_LIMIT_PER_MIN", 60)
 
 	cfg.APIKeys = splitNonEmpty(getenv("AGENT_API_KEYS", ""))
+	cfg.AuthKeysFile = getenv("AGENT_AUTH_KEYS_FILE", "")
 
 	cfg.EnableFS = getenvBool("AGENT_ENABLE_FS", false)
 	cfg.AllowDirs = splitNonEmpty(getenv("AGENT_ALLOW_DIRS", ""))
 	if len(cfg.AllowDirs) == 0 {
 		cfg.AllowDirs = []string{"."}
 	}
+	cfg.MaxWriteBytes = int64(getenvInt("AGENT_MAX_WRITE_BYTES", 1024*1024))
+
+	cfg.RunAllowExec = splitNonEmpty(getenv("AGENT_RUN_ALLOW", ""))
+	cfg.RunDenyExec = splitNonEmpty(getenv("AGENT_RUN_DENY", ""))
+	cfg.RunTimeout = time.Duration(getenvInt("AGENT_RUN_TIMEOUT_SECONDS", 30)) * time.Second
+	cfg.RunMaxOutputBytes = getenvInt("AGENT_RUN_MAX_OUTPUT_BYTES", 256*1024)
+	cfg.RunAllowEnv = splitNonEmpty(getenv("AGENT_RUN_ALLOW_ENV", "PATH,HOME,LANG,TMPDIR"))
+	cfg.RunConfineCwd = getenvBool("AGENT_RUN_CONFINE_CWD", false)
+	cfg.RunLimitCPUSeconds = getenvInt("AGENT_RUN_LIMIT_CPU_SECONDS", 0)
+	cfg.RunLimitAddressSpaceMB = getenvInt("AGENT_RUN_LIMIT_AS_MB", 0)
+	cfg.RunLimitNoFile = getenvInt("AGENT_RUN_LIMIT_NOFILE", 0)
+
+	cfg.Providers = parseProviderSpecs(getenv("AGENT_PROVIDERS", ""))
+	if len(cfg.Providers) == 0 {
+		cfg.Providers = []ProviderSpec{{
+			Scheme:  "ollama",
+			BaseURL: cfg.OllamaHost,
+			Models:  []string{cfg.Model, cfg.EmbedModel},
+		}}
+	}
+
+	cfg.HNSWM = getenvInt("AGENT_HNSW_M", 16)
+	cfg.HNSWEfConstruction = getenvInt("AGENT_HNSW_EF_CONSTRUCTION", 200)
+	cfg.HNSWEfSearch = getenvInt("AGENT_HNSW_EF_SEARCH", 64)
 
 	cfg.AuditLogPath = getenv("AGENT_AUDIT_LOG", "agent_audit.log")
+	cfg.AuditMaxBytes = int64(getenvInt("AGENT_AUDIT_MAX_BYTES", 10*1024*1024))
+	cfg.AuditMaxAgeHours = getenvInt("AGENT_AUDIT_MAX_AGE_HOURS", 168)
+	cfg.AuditRedactFields = getenv("AGENT_AUDIT_REDACT_FIELDS", "")
+	cfg.AuditSyslogTag = getenv("AGENT_AUDIT_SYSLOG_TAG", "")
+	cfg.AuditWebhookURL = getenv("AGENT_AUDIT_WEBHOOK_URL", "")
 	return cfg
 }
 
@@ -85,6 +189,37 @@ func getenvBool(key string, def bool) bool {
 	return v == "1" || v == "true" || v == "yes" || v == "on"
 }
 
+// parseProviderSpecs parses AGENT_PROVIDERS -- see ProviderSpec's doc
+// comment for the format. Entries with no scheme, or that fail to parse,
+// are skipped rather than erroring, matching splitNonEmpty's tolerance of
+// stray separators.
+func parseProviderSpecs(raw string) []ProviderSpec {
+	var out []ProviderSpec
+	for _, entry := range strings.Split(raw, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, "|", 4)
+		scheme := strings.TrimSpace(parts[0])
+		if scheme == "" {
+			continue
+		}
+		spec := ProviderSpec{Scheme: scheme}
+		if len(parts) > 1 {
+			spec.BaseURL = strings.TrimSpace(parts[1])
+		}
+		if len(parts) > 2 {
+			spec.APIKey = strings.TrimSpace(parts[2])
+		}
+		if len(parts) > 3 {
+			spec.Models = splitNonEmpty(parts[3])
+		}
+		out = append(out, spec)
+	}
+	return out
+}
+
 func splitNonEmpty(s string) []string {
 	parts := strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' || r == ' ' || r == '\n' || r == '\t' })
 	out := make([]string, 0, len(parts))
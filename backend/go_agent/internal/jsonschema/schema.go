@@ -0,0 +1,43 @@
+// Package jsonschema defines a minimal JSON Schema representation used to
+// describe tool parameters to the model and to callers of /v1/tools/schema.
+// It is intentionally a small subset of the draft-07 spec -- just enough to
+// describe primitive args, objects and arrays -- rather than a general
+// validator.
+package jsonschema
+
+// Schema mirrors the handful of JSON Schema keywords the agent tools need.
+type Schema struct {
+	Type        string            `json:"type"`
+	Description string            `json:"description,omitempty"`
+	Properties  map[string]Schema `json:"properties,omitempty"`
+	Items       *Schema           `json:"items,omitempty"`
+	Required    []string          `json:"required,omitempty"`
+	Enum        []string          `json:"enum,omitempty"`
+}
+
+// String returns a Schema describing a plain string argument.
+func String(desc string) Schema {
+	return Schema{Type: "string", Description: desc}
+}
+
+// StringEnum returns a Schema describing a string argument restricted to a
+// fixed set of values.
+func StringEnum(desc string, values ...string) Schema {
+	return Schema{Type: "string", Description: desc, Enum: values}
+}
+
+// Integer returns a Schema describing an integer argument.
+func Integer(desc string) Schema {
+	return Schema{Type: "integer", Description: desc}
+}
+
+// Boolean returns a Schema describing a boolean argument.
+func Boolean(desc string) Schema {
+	return Schema{Type: "boolean", Description: desc}
+}
+
+// Object builds an "object" Schema out of named properties, marking as
+// required only the property names passed in required.
+func Object(desc string, props map[string]Schema, required ...string) Schema {
+	return Schema{Type: "object", Description: desc, Properties: props, Required: required}
+}
@@ -1,45 +1,157 @@
+// Package limits rate-limits HTTP requests per client key using the generic
+// cell rate algorithm (GCRA) -- a token bucket's behavior without needing to
+// store (and periodically refill) a bucket per key.
 package limits
 
 import (
+	"container/list"
+	"fmt"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
-
-	"golang.org/x/time/rate"
 )
 
+// maxKeys bounds the tracked-client map; past this, the least-recently-used
+// key is evicted so a long-running server with many distinct IPs/API keys
+// doesn't leak memory.
+const maxKeys = 10000
+
+// Tier configures one rate limit: PerMin requests per minute sustained
+// indefinitely, with up to Burst requests in total allowed in a single
+// instant before throttling kicks in (defaults to PerMin if unset).
+type Tier struct {
+	PerMin int
+	Burst  int
+}
+
+func (t Tier) normalize() Tier {
+	if t.PerMin <= 0 {
+		t.PerMin = 60
+	}
+	if t.Burst <= 0 {
+		t.Burst = t.PerMin
+	}
+	return t
+}
+
+// KeyedLimiter rate-limits per client key via GCRA: each key tracks a
+// single "theoretical arrival time" (tat), updated on every request as
+// tat' = max(now, tat) + emissionInterval. The request is allowed iff
+// tat' - now <= burst*emissionInterval; otherwise it's rejected with a
+// Retry-After computed from how far over that allowance it landed.
+//
+// Routes assigns stricter or looser Tiers to specific paths (e.g. a chat
+// endpoint vs. a status check); anything not listed uses Default.
 type KeyedLimiter struct {
-	mu       sync.Mutex
-	limiters map[string]*rate.Limiter
-	rate     rate.Limit
-	burst    int
-	window   time.Duration
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // front = most recently used
+
+	Default Tier
+	Routes  map[string]Tier
 }
 
+type limiterEntry struct {
+	key string
+	tat time.Time
+}
+
+// NewKeyedLimiter builds a limiter with a single default tier and no
+// per-route overrides, matching the previous single-tier behavior.
 func NewKeyedLimiter(perMin int) *KeyedLimiter {
-	if perMin <= 0 {
-		perMin = 60
-	}
-	lim := rate.Limit(float64(perMin) / 60.0)
+	return NewTieredLimiter(Tier{PerMin: perMin}, nil)
+}
+
+// NewTieredLimiter builds a limiter with def as the fallback tier and
+// routes mapping request paths to stricter or looser tiers.
+func NewTieredLimiter(def Tier, routes map[string]Tier) *KeyedLimiter {
 	return &KeyedLimiter{
-		limiters: map[string]*rate.Limiter{},
-		rate:     lim,
-		burst:    perMin,
-		window:   time.Minute,
+		entries: map[string]*list.Element{},
+		order:   list.New(),
+		Default: def.normalize(),
+		Routes:  routes,
 	}
 }
 
-func (k *KeyedLimiter) get(key string) *rate.Limiter {
+func (k *KeyedLimiter) tierFor(path string) Tier {
+	if t, ok := k.Routes[path]; ok {
+		return t.normalize()
+	}
+	return k.Default
+}
+
+// decision is what allow reports back to the middleware: whether the
+// request is let through, the X-RateLimit-* header values to emit, and --
+// when denied -- how long the caller should wait before retrying.
+type decision struct {
+	allowed      bool
+	limit        int
+	remaining    int
+	resetSeconds int
+	retryAfter   time.Duration
+}
+
+// allow applies GCRA for key under tier at time now.
+func (k *KeyedLimiter) allow(key string, tier Tier, now time.Time) decision {
+	emissionInterval := time.Minute / time.Duration(tier.PerMin)
+	burstWindow := emissionInterval * time.Duration(tier.Burst)
+
 	k.mu.Lock()
 	defer k.mu.Unlock()
-	l, ok := k.limiters[key]
-	if !ok {
-		l = rate.NewLimiter(k.rate, k.burst)
-		k.limiters[key] = l
+
+	tat := now
+	if el, found := k.entries[key]; found {
+		if e := el.Value.(*limiterEntry).tat; e.After(tat) {
+			tat = e
+		}
+	}
+
+	newTat := tat.Add(emissionInterval)
+	allowedAt := newTat.Add(-burstWindow)
+	if allowedAt.After(now) {
+		// Over the burst allowance: reject without advancing tat, so the
+		// caller isn't punished further just for asking too soon.
+		k.touch(key, tat)
+		return decision{
+			limit:        tier.PerMin,
+			resetSeconds: int(tat.Sub(now) / time.Second),
+			retryAfter:   allowedAt.Sub(now),
+		}
+	}
+
+	k.touch(key, newTat)
+	remaining := int((burstWindow - newTat.Sub(now)) / emissionInterval)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return decision{
+		allowed:      true,
+		limit:        tier.PerMin,
+		remaining:    remaining,
+		resetSeconds: int(newTat.Sub(now) / time.Second),
+	}
+}
+
+// touch records tat for key and marks it most-recently-used, evicting the
+// least-recently-used key if the map is now over maxKeys.
+func (k *KeyedLimiter) touch(key string, tat time.Time) {
+	if el, found := k.entries[key]; found {
+		el.Value.(*limiterEntry).tat = tat
+		k.order.MoveToFront(el)
+		return
+	}
+	el := k.order.PushFront(&limiterEntry{key: key, tat: tat})
+	k.entries[key] = el
+	if k.order.Len() > maxKeys {
+		oldest := k.order.Back()
+		if oldest != nil {
+			k.order.Remove(oldest)
+			delete(k.entries, oldest.Value.(*limiterEntry).key)
+		}
 	}
-	return l
 }
 
 func ClientKey(r *http.Request) string {
@@ -65,8 +177,16 @@ func ClientKey(r *http.Request) string {
 func (k *KeyedLimiter) Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		key := ClientKey(r)
-		lim := k.get(key)
-		if !lim.Allow() {
+		tier := k.tierFor(r.URL.Path)
+		d := k.allow(key, tier, time.Now())
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(d.limit))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(d.remaining))
+		h.Set("X-RateLimit-Reset", strconv.Itoa(d.resetSeconds))
+
+		if !d.allowed {
+			h.Set("Retry-After", fmt.Sprintf("%.0f", d.retryAfter.Seconds()))
 			w.WriteHeader(http.StatusTooManyRequests)
 			_, _ = w.Write([]byte("rate limit exceeded"))
 			return
@@ -0,0 +1,84 @@
+package limits
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAllowPermitsBurstThenRejects(t *testing.T) {
+	k := NewTieredLimiter(Tier{PerMin: 60, Burst: 3}, nil)
+	now := time.Unix(0, 0)
+
+	for i := 0; i < 3; i++ {
+		d := k.allow("client", k.Default, now)
+		if !d.allowed {
+			t.Fatalf("request %d: allow = false, want true (within burst of 3)", i+1)
+		}
+	}
+
+	d := k.allow("client", k.Default, now)
+	if d.allowed {
+		t.Fatalf("4th immediate request: allow = true, want false (burst exhausted)")
+	}
+}
+
+func TestAllowRetryAfterMatchesEmissionInterval(t *testing.T) {
+	// PerMin: 60, Burst: 1 -> one request per second, no slack.
+	k := NewTieredLimiter(Tier{PerMin: 60, Burst: 1}, nil)
+	now := time.Unix(0, 0)
+
+	if d := k.allow("client", k.Default, now); !d.allowed {
+		t.Fatalf("first request should be allowed")
+	}
+	d := k.allow("client", k.Default, now)
+	if d.allowed {
+		t.Fatalf("second immediate request should be rejected")
+	}
+	if d.retryAfter != time.Second {
+		t.Fatalf("retryAfter = %v, want 1s (60 PerMin emission interval)", d.retryAfter)
+	}
+}
+
+func TestAllowResetsAfterEmissionInterval(t *testing.T) {
+	k := NewTieredLimiter(Tier{PerMin: 60, Burst: 1}, nil)
+	now := time.Unix(0, 0)
+
+	if d := k.allow("client", k.Default, now); !d.allowed {
+		t.Fatalf("first request should be allowed")
+	}
+	later := now.Add(time.Second)
+	if d := k.allow("client", k.Default, later); !d.allowed {
+		t.Fatalf("request one full emission interval later should be allowed")
+	}
+}
+
+func TestAllowTracksKeysIndependently(t *testing.T) {
+	k := NewTieredLimiter(Tier{PerMin: 60, Burst: 1}, nil)
+	now := time.Unix(0, 0)
+
+	if d := k.allow("a", k.Default, now); !d.allowed {
+		t.Fatalf("client a's first request should be allowed")
+	}
+	if d := k.allow("b", k.Default, now); !d.allowed {
+		t.Fatalf("client b's first request should be allowed independently of a")
+	}
+}
+
+func TestTierForUsesRouteOverride(t *testing.T) {
+	k := NewTieredLimiter(Tier{PerMin: 60}, map[string]Tier{
+		"/v1/ask": {PerMin: 10},
+	})
+	if got := k.tierFor("/v1/ask"); got.PerMin != 10 {
+		t.Fatalf("tierFor(/v1/ask).PerMin = %d, want 10", got.PerMin)
+	}
+	if got := k.tierFor("/v1/status"); got.PerMin != 60 {
+		t.Fatalf("tierFor(/v1/status).PerMin = %d, want 60 (default)", got.PerMin)
+	}
+}
+
+func TestTierNormalizeDefaultsBurstToPerMin(t *testing.T) {
+	got := Tier{PerMin: 30}.normalize()
+	if got.Burst != 30 {
+		t.Fatalf("normalize() Burst = %d, want 30 (defaulted to PerMin)", got.Burst)
+	}
+}
@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// anthropicAPIVersion pins the Messages API revision this adapter was
+// written against.
+const anthropicAPIVersion = "2023-06-01"
+
+// anthropicMaxTokens bounds a single completion; the Messages API requires
+// max_tokens on every request and has no "until done" default.
+const anthropicMaxTokens = 4096
+
+// anthropicProvider adapts Anthropic's Messages API. Embed is unsupported --
+// Anthropic doesn't offer an embeddings endpoint -- and always returns an
+// error. memory.Store does not fall back to another provider, so with this
+// as the active provider Store.Add fails and Store.Query returns nothing.
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	models  []string
+	http    *http.Client
+}
+
+// NewAnthropicProvider builds a Provider against the Anthropic API (or a
+// compatible baseURL override), authenticating with apiKey.
+func NewAnthropicProvider(baseURL, apiKey string, models []string) Provider {
+	if baseURL == "" {
+		baseURL = "https://api.anthropic.com"
+	}
+	return &anthropicProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		models:  models,
+		http:    &http.Client{},
+	}
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+	Stream    bool               `json:"stream"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+func (p *anthropicProvider) newRequest(ctx context.Context, body anthropicRequest) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+	return req, nil
+}
+
+func (p *anthropicProvider) Generate(ctx context.Context, model, prompt string) (string, error) {
+	req, err := p.newRequest(ctx, anthropicRequest{
+		Model:     model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", statusError("messages", resp)
+	}
+	var out anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if len(out.Content) == 0 {
+		return "", errors.New("anthropic: empty content")
+	}
+	var b strings.Builder
+	for _, c := range out.Content {
+		b.WriteString(c.Text)
+	}
+	return b.String(), nil
+}
+
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Text string `json:"text"`
+	} `json:"delta"`
+}
+
+// GenerateStream decodes the Messages API's SSE stream, forwarding each
+// content_block_delta's text and stopping at message_stop.
+func (p *anthropicProvider) GenerateStream(ctx context.Context, model, prompt string) (<-chan Token, error) {
+	req, err := p.newRequest(ctx, anthropicRequest{
+		Model:     model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+		Stream:    true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := statusError("messages", resp)
+		resp.Body.Close()
+		return nil, err
+	}
+
+	out := make(chan Token)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			var ev anthropicStreamEvent
+			if err := json.Unmarshal([]byte(data), &ev); err != nil {
+				out <- Token{Error: err.Error()}
+				return
+			}
+			switch ev.Type {
+			case "content_block_delta":
+				out <- Token{Response: ev.Delta.Text}
+			case "message_stop":
+				out <- Token{Done: true}
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			out <- Token{Error: err.Error()}
+		}
+	}()
+	return out, nil
+}
+
+func (p *anthropicProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	return nil, fmt.Errorf("anthropic: embeddings not supported")
+}
+
+func (p *anthropicProvider) Models() []string {
+	return p.models
+}
@@ -0,0 +1,46 @@
+package llm
+
+import (
+	"context"
+
+	"yogz/go_agent/internal/ollama"
+)
+
+// ollamaProvider adapts an ollama.Client to the Provider interface.
+type ollamaProvider struct {
+	client *ollama.Client
+	models []string
+}
+
+// NewOllamaProvider builds a Provider backed by an Ollama server at baseURL,
+// advertising models as its known aliases (used by ModelTool's listing).
+func NewOllamaProvider(baseURL string, models []string) Provider {
+	return &ollamaProvider{client: ollama.New(baseURL), models: models}
+}
+
+func (p *ollamaProvider) Generate(ctx context.Context, model, prompt string) (string, error) {
+	return p.client.Generate(ctx, model, prompt)
+}
+
+func (p *ollamaProvider) GenerateStream(ctx context.Context, model, prompt string) (<-chan Token, error) {
+	toks, err := p.client.GenerateStream(ctx, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan Token)
+	go func() {
+		defer close(out)
+		for t := range toks {
+			out <- Token{Response: t.Response, Done: t.Done, EvalCount: t.EvalCount, Error: t.Error}
+		}
+	}()
+	return out, nil
+}
+
+func (p *ollamaProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	return p.client.Embed(ctx, model, text)
+}
+
+func (p *ollamaProvider) Models() []string {
+	return p.models
+}
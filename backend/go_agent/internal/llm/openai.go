@@ -0,0 +1,207 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// openaiProvider adapts any server speaking the OpenAI chat-completions and
+// embeddings API -- OpenAI itself, or llama.cpp's built-in server, which
+// mirrors that wire format.
+type openaiProvider struct {
+	baseURL string
+	apiKey  string
+	models  []string
+	http    *http.Client
+}
+
+// NewOpenAIProvider builds a Provider against an OpenAI-compatible server at
+// baseURL, authenticating with apiKey (sent as a Bearer token; omitted if
+// empty, which is normal for a local llama.cpp server).
+func NewOpenAIProvider(baseURL, apiKey string, models []string) Provider {
+	return &openaiProvider{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		models:  models,
+		http:    &http.Client{},
+	}
+}
+
+type openaiChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openaiChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []openaiChatMessage `json:"messages"`
+	Stream   bool                `json:"stream"`
+}
+
+type openaiChatChoice struct {
+	Delta struct {
+		Content string `json:"content"`
+	} `json:"delta"`
+	Message struct {
+		Content string `json:"content"`
+	} `json:"message"`
+	FinishReason string `json:"finish_reason"`
+}
+
+type openaiChatResponse struct {
+	Choices []openaiChatChoice `json:"choices"`
+}
+
+func (p *openaiProvider) newRequest(ctx context.Context, path string, body any) (*http.Request, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	return req, nil
+}
+
+func (p *openaiProvider) Generate(ctx context.Context, model, prompt string) (string, error) {
+	req, err := p.newRequest(ctx, "/v1/chat/completions", openaiChatRequest{
+		Model:    model,
+		Messages: []openaiChatMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", statusError("chat completion", resp)
+	}
+	var out openaiChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	if len(out.Choices) == 0 {
+		return "", errors.New("openai: empty choices")
+	}
+	return out.Choices[0].Message.Content, nil
+}
+
+// GenerateStream decodes the provider's "data: {...}" SSE chunks until a
+// "data: [DONE]" line or the stream closes.
+func (p *openaiProvider) GenerateStream(ctx context.Context, model, prompt string) (<-chan Token, error) {
+	req, err := p.newRequest(ctx, "/v1/chat/completions", openaiChatRequest{
+		Model:    model,
+		Messages: []openaiChatMessage{{Role: "user", Content: prompt}},
+		Stream:   true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := statusError("chat completion", resp)
+		resp.Body.Close()
+		return nil, err
+	}
+
+	out := make(chan Token)
+	go func() {
+		defer close(out)
+		defer resp.Body.Close()
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || !strings.HasPrefix(line, "data:") {
+				continue
+			}
+			data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+			if data == "[DONE]" {
+				out <- Token{Done: true}
+				return
+			}
+			var chunk openaiChatResponse
+			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+				out <- Token{Error: err.Error()}
+				return
+			}
+			if len(chunk.Choices) == 0 {
+				continue
+			}
+			c := chunk.Choices[0]
+			done := c.FinishReason != ""
+			out <- Token{Response: c.Delta.Content, Done: done}
+			if done {
+				return
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			out <- Token{Error: err.Error()}
+		}
+	}()
+	return out, nil
+}
+
+type openaiEmbeddingsRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type openaiEmbeddingsResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+}
+
+func (p *openaiProvider) Embed(ctx context.Context, model, text string) ([]float64, error) {
+	req, err := p.newRequest(ctx, "/v1/embeddings", openaiEmbeddingsRequest{Model: model, Input: text})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, statusError("embeddings", resp)
+	}
+	var out openaiEmbeddingsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, err
+	}
+	if len(out.Data) == 0 || len(out.Data[0].Embedding) == 0 {
+		return nil, errors.New("openai: empty embedding")
+	}
+	return out.Data[0].Embedding, nil
+}
+
+func (p *openaiProvider) Models() []string {
+	return p.models
+}
+
+func statusError(op string, resp *http.Response) error {
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	msg := strings.TrimSpace(string(b))
+	if msg == "" {
+		return fmt.Errorf("openai %s failed (status=%d)", op, resp.StatusCode)
+	}
+	return fmt.Errorf("openai %s failed (status=%d): %s", op, resp.StatusCode, msg)
+}
@@ -0,0 +1,34 @@
+// Package llm abstracts over model backends (Ollama, hosted chat APIs, a
+// local llama.cpp server, ...) behind a single Provider interface, so the
+// agent and memory layers don't hardcode an *ollama.Client. A Registry
+// resolves the scheme from config.ProviderSpec (ollama, openai, anthropic,
+// llamacpp) to the concrete adapter.
+package llm
+
+import "context"
+
+// Token is one chunk of a streamed generation. It mirrors ollama.Token so
+// the Ollama adapter can pass chunks through with a plain field copy.
+type Token struct {
+	Response  string
+	Done      bool
+	EvalCount int
+	Error     string
+}
+
+// Provider is a model backend capable of text generation and embeddings.
+// Implementations wrap a specific API; callers select a model by name from
+// whatever that provider's Models() advertises.
+type Provider interface {
+	// Generate returns a complete response for prompt using model.
+	Generate(ctx context.Context, model, prompt string) (string, error)
+	// GenerateStream streams the response as it's produced; the channel is
+	// closed once the provider reports done, the context ends, or the
+	// provider gives up (a final Token carrying Error is sent first).
+	GenerateStream(ctx context.Context, model, prompt string) (<-chan Token, error)
+	// Embed returns an embedding vector for text using model. Providers
+	// that don't support embeddings return an error.
+	Embed(ctx context.Context, model, text string) ([]float64, error)
+	// Models lists the model aliases this provider was configured with.
+	Models() []string
+}
@@ -0,0 +1,78 @@
+package llm
+
+import (
+	"fmt"
+
+	"yogz/go_agent/internal/config"
+)
+
+// Registry resolves a scheme (e.g. "ollama", "openai") to the Provider
+// configured for it.
+type Registry struct {
+	providers map[string]Provider
+	order     []string
+}
+
+func NewRegistry() *Registry {
+	return &Registry{providers: map[string]Provider{}}
+}
+
+func (r *Registry) Register(scheme string, p Provider) {
+	if _, exists := r.providers[scheme]; !exists {
+		r.order = append(r.order, scheme)
+	}
+	r.providers[scheme] = p
+}
+
+func (r *Registry) Get(scheme string) (Provider, bool) {
+	p, ok := r.providers[scheme]
+	return p, ok
+}
+
+// Default returns the first registered provider, for callers (the CLI, the
+// single-model Agent flow) that haven't picked a specific scheme.
+func (r *Registry) Default() (Provider, error) {
+	if len(r.order) == 0 {
+		return nil, fmt.Errorf("no llm providers configured")
+	}
+	return r.providers[r.order[0]], nil
+}
+
+// Models returns every model alias across all registered providers, in
+// registration order.
+func (r *Registry) Models() []string {
+	var out []string
+	for _, scheme := range r.order {
+		out = append(out, r.providers[scheme].Models()...)
+	}
+	return out
+}
+
+// BuildRegistry constructs a Registry from config.Config's provider specs,
+// one adapter per spec, keyed by its scheme.
+func BuildRegistry(specs []config.ProviderSpec) (*Registry, error) {
+	reg := NewRegistry()
+	for _, spec := range specs {
+		p, err := newProvider(spec)
+		if err != nil {
+			return nil, err
+		}
+		reg.Register(spec.Scheme, p)
+	}
+	return reg, nil
+}
+
+func newProvider(spec config.ProviderSpec) (Provider, error) {
+	switch spec.Scheme {
+	case "", "ollama":
+		return NewOllamaProvider(spec.BaseURL, spec.Models), nil
+	case "openai", "llamacpp":
+		// llama.cpp's built-in server speaks the OpenAI chat-completions API,
+		// so one adapter covers both.
+		return NewOpenAIProvider(spec.BaseURL, spec.APIKey, spec.Models), nil
+	case "anthropic":
+		return NewAnthropicProvider(spec.BaseURL, spec.APIKey, spec.Models), nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider scheme: %q", spec.Scheme)
+	}
+}
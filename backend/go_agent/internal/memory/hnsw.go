@@ -0,0 +1,329 @@
+package memory
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// hnswConfig bundles the tunables that shape graph construction and search
+// quality/speed tradeoffs. Mmax bounds neighbors per node on layers > 0;
+// Mmax0 is the layer-0 cap, conventionally 2*M since layer 0 carries most of
+// the graph's connectivity.
+type hnswConfig struct {
+	M              int
+	Mmax           int
+	Mmax0          int
+	EfConstruction int
+	EfSearch       int
+	mL             float64
+}
+
+func newHNSWConfig(m, efConstruction, efSearch int) hnswConfig {
+	if m <= 0 {
+		m = 16
+	}
+	if efConstruction <= 0 {
+		efConstruction = 200
+	}
+	if efSearch <= 0 {
+		efSearch = 64
+	}
+	return hnswConfig{
+		M:              m,
+		Mmax:           m,
+		Mmax0:          m * 2,
+		EfConstruction: efConstruction,
+		EfSearch:       efSearch,
+		mL:             1 / math.Log(float64(m)),
+	}
+}
+
+type hnswNode struct {
+	vector []float64
+	level  int
+	// neighbors[layer] holds this node's neighbor ids at that layer.
+	neighbors [][]int
+}
+
+// hnswIndex is an in-memory HNSW graph over a caller-owned set of vectors,
+// addressed by caller-assigned integer ids. It only ever grows via Insert;
+// HNSW doesn't support cheap node removal, so Store rebuilds it from scratch
+// (reset + reinsert every surviving entry) whenever entries are evicted.
+type hnswIndex struct {
+	mu  sync.RWMutex
+	cfg hnswConfig
+
+	nodes    map[int]*hnswNode
+	entry    int
+	entryLvl int
+	hasEntry bool
+}
+
+func newHNSWIndex(cfg hnswConfig) *hnswIndex {
+	return &hnswIndex{cfg: cfg, nodes: map[int]*hnswNode{}}
+}
+
+func (h *hnswIndex) reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.nodes = map[int]*hnswNode{}
+	h.hasEntry = false
+}
+
+func (h *hnswIndex) randomLevel() int {
+	return int(math.Floor(-math.Log(rand.Float64()) * h.cfg.mL))
+}
+
+// distance is 1-cosine similarity, so smaller means closer; callers never
+// need to know which similarity measure backs it.
+func (h *hnswIndex) distance(a, b []float64) float64 {
+	return 1 - cosine(a, b)
+}
+
+// Insert adds id/vec to the graph, sampling its level and wiring it into
+// every layer from 0 up to that level following the standard HNSW
+// construction algorithm: greedy descent to find an entry point on layers
+// above the new node's level, then a beam search plus diverse-neighbor
+// selection on layers at or below it.
+func (h *hnswIndex) Insert(id int, vec []float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	level := h.randomLevel()
+	node := &hnswNode{vector: vec, level: level, neighbors: make([][]int, level+1)}
+	h.nodes[id] = node
+
+	if !h.hasEntry {
+		h.entry, h.entryLvl, h.hasEntry = id, level, true
+		return
+	}
+
+	cur := h.entry
+	curDist := h.distance(vec, h.nodes[cur].vector)
+	for lc := h.entryLvl; lc > level; lc-- {
+		cur, curDist = h.greedyDescend(cur, curDist, vec, lc)
+	}
+
+	top := level
+	if h.entryLvl < top {
+		top = h.entryLvl
+	}
+	for lc := top; lc >= 0; lc-- {
+		candidates := h.searchLayer(vec, cur, h.cfg.EfConstruction, lc)
+		neighbors := h.selectNeighbors(vec, candidates, h.cfg.M)
+		node.neighbors[lc] = neighbors
+		for _, nb := range neighbors {
+			h.connect(nb, id, lc)
+		}
+		if len(candidates) > 0 {
+			cur = candidates[0].id
+		}
+	}
+
+	if level > h.entryLvl {
+		h.entry, h.entryLvl = id, level
+	}
+}
+
+// Search returns up to k ids nearest to query, via greedy descent down to
+// layer 0 followed by an EfSearch-sized beam there.
+func (h *hnswIndex) Search(query []float64, k int) []int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	if !h.hasEntry {
+		return nil
+	}
+	cur := h.entry
+	curDist := h.distance(query, h.nodes[cur].vector)
+	for lc := h.entryLvl; lc > 0; lc-- {
+		cur, curDist = h.greedyDescend(cur, curDist, query, lc)
+	}
+
+	ef := h.cfg.EfSearch
+	if ef < k {
+		ef = k
+	}
+	candidates := h.searchLayer(query, cur, ef, 0)
+	if len(candidates) > k {
+		candidates = candidates[:k]
+	}
+	ids := make([]int, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.id
+	}
+	return ids
+}
+
+// greedyDescend walks from cur toward query on a single layer, following
+// whichever neighbor is closer, until no neighbor improves on cur.
+func (h *hnswIndex) greedyDescend(cur int, curDist float64, query []float64, layer int) (int, float64) {
+	for {
+		improved := false
+		node := h.nodes[cur]
+		if layer >= len(node.neighbors) {
+			return cur, curDist
+		}
+		for _, nb := range node.neighbors[layer] {
+			nbNode, ok := h.nodes[nb]
+			if !ok {
+				continue
+			}
+			if d := h.distance(query, nbNode.vector); d < curDist {
+				cur, curDist, improved = nb, d, true
+			}
+		}
+		if !improved {
+			return cur, curDist
+		}
+	}
+}
+
+type hnswCandidate struct {
+	id   int
+	dist float64
+}
+
+type hnswMinHeap []hnswCandidate
+
+func (h hnswMinHeap) Len() int            { return len(h) }
+func (h hnswMinHeap) Less(i, j int) bool  { return h[i].dist < h[j].dist }
+func (h hnswMinHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hnswMinHeap) Push(x interface{}) { *h = append(*h, x.(hnswCandidate)) }
+func (h *hnswMinHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+type hnswMaxHeap []hnswCandidate
+
+func (h hnswMaxHeap) Len() int            { return len(h) }
+func (h hnswMaxHeap) Less(i, j int) bool  { return h[i].dist > h[j].dist }
+func (h hnswMaxHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hnswMaxHeap) Push(x interface{}) { *h = append(*h, x.(hnswCandidate)) }
+func (h *hnswMaxHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// searchLayer runs the standard HNSW beam search: candidates is a min-heap
+// exploration frontier, results a max-heap of the best ef found so far
+// (its root is the current worst kept result, used to decide when to stop
+// exploring). It returns results sorted ascending by distance.
+func (h *hnswIndex) searchLayer(query []float64, entryID int, ef int, layer int) []hnswCandidate {
+	entryDist := h.distance(query, h.nodes[entryID].vector)
+	visited := map[int]bool{entryID: true}
+
+	candidates := &hnswMinHeap{{id: entryID, dist: entryDist}}
+	results := &hnswMaxHeap{{id: entryID, dist: entryDist}}
+
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if results.Len() >= ef && c.dist > (*results)[0].dist {
+			break
+		}
+		node, ok := h.nodes[c.id]
+		if !ok || layer >= len(node.neighbors) {
+			continue
+		}
+		for _, nb := range node.neighbors[layer] {
+			if visited[nb] {
+				continue
+			}
+			visited[nb] = true
+			nbNode, ok := h.nodes[nb]
+			if !ok {
+				continue
+			}
+			d := h.distance(query, nbNode.vector)
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(candidates, hnswCandidate{id: nb, dist: d})
+				heap.Push(results, hnswCandidate{id: nb, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+
+	out := make([]hnswCandidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(hnswCandidate)
+	}
+	return out
+}
+
+// selectNeighbors applies the diversity heuristic from the HNSW paper:
+// candidates are considered closest-first, and a candidate is kept only if
+// it's closer to query than to every neighbor already selected -- this
+// favors spreading neighbors across directions over clustering them all on
+// the query's near side.
+func (h *hnswIndex) selectNeighbors(query []float64, candidates []hnswCandidate, m int) []int {
+	selected := make([]hnswCandidate, 0, m)
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		cNode, ok := h.nodes[c.id]
+		if !ok {
+			continue
+		}
+		keep := true
+		for _, s := range selected {
+			sNode := h.nodes[s.id]
+			if h.distance(cNode.vector, sNode.vector) < c.dist {
+				keep = false
+				break
+			}
+		}
+		if keep {
+			selected = append(selected, c)
+		}
+	}
+	ids := make([]int, len(selected))
+	for i, s := range selected {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+// connect adds a directed edge from id to other at layer (Insert adds the
+// edge in the other direction itself), pruning id's
+// neighbor list back down to the layer's degree cap (Mmax0 at layer 0, Mmax
+// above) via the same diversity heuristic used at insert time.
+func (h *hnswIndex) connect(id, other int, layer int) {
+	node, ok := h.nodes[id]
+	if !ok {
+		return
+	}
+	for len(node.neighbors) <= layer {
+		node.neighbors = append(node.neighbors, nil)
+	}
+	node.neighbors[layer] = append(node.neighbors[layer], other)
+
+	maxDeg := h.cfg.Mmax
+	if layer == 0 {
+		maxDeg = h.cfg.Mmax0
+	}
+	if len(node.neighbors[layer]) <= maxDeg {
+		return
+	}
+
+	cands := make([]hnswCandidate, 0, len(node.neighbors[layer]))
+	for _, nb := range node.neighbors[layer] {
+		if nbNode, ok := h.nodes[nb]; ok {
+			cands = append(cands, hnswCandidate{id: nb, dist: h.distance(node.vector, nbNode.vector)})
+		}
+	}
+	sort.Slice(cands, func(i, j int) bool { return cands[i].dist < cands[j].dist })
+	node.neighbors[layer] = h.selectNeighbors(node.vector, cands, maxDeg)
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestHNSWSearchReturnsExactNearestNeighbor(t *testing.T) {
+	idx := newHNSWIndex(newHNSWConfig(16, 200, 64))
+	idx.Insert(1, []float64{1, 0, 0})
+	idx.Insert(2, []float64{0, 1, 0})
+	idx.Insert(3, []float64{0, 0, 1})
+	idx.Insert(4, []float64{0.9, 0.1, 0})
+
+	got := idx.Search([]float64{1, 0, 0}, 1)
+	if len(got) != 1 || got[0] != 1 {
+		t.Fatalf("Search(closest to [1,0,0]) = %v, want [1]", got)
+	}
+}
+
+func TestHNSWSearchRespectsK(t *testing.T) {
+	idx := newHNSWIndex(newHNSWConfig(16, 200, 64))
+	for i := 0; i < 20; i++ {
+		idx.Insert(i, []float64{float64(i), 0, 0})
+	}
+	got := idx.Search([]float64{0, 0, 0}, 5)
+	if len(got) != 5 {
+		t.Fatalf("Search(k=5) returned %d ids, want 5", len(got))
+	}
+}
+
+func TestHNSWSearchOnEmptyIndex(t *testing.T) {
+	idx := newHNSWIndex(newHNSWConfig(16, 200, 64))
+	if got := idx.Search([]float64{1, 2, 3}, 3); got != nil {
+		t.Fatalf("Search on empty index = %v, want nil", got)
+	}
+}
+
+// TestHNSWRecallAgainstBruteForce builds a larger random index and checks
+// that approximate search agrees with brute-force cosine search on most
+// queries -- HNSW trades some recall for speed, so this allows a small
+// miss rate rather than requiring exact agreement every time.
+func TestHNSWRecallAgainstBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	const n = 200
+	vecs := make(map[int][]float64, n)
+	idx := newHNSWIndex(newHNSWConfig(16, 200, 64))
+	for i := 0; i < n; i++ {
+		v := []float64{rng.Float64(), rng.Float64(), rng.Float64(), rng.Float64()}
+		vecs[i] = v
+		idx.Insert(i, v)
+	}
+
+	hits, total := 0, 50
+	for q := 0; q < total; q++ {
+		query := []float64{rng.Float64(), rng.Float64(), rng.Float64(), rng.Float64()}
+		bestID, bestDist := -1, -1.0
+		for id, v := range vecs {
+			if d := 1 - cosine(query, v); bestID == -1 || d < bestDist {
+				bestID, bestDist = id, d
+			}
+		}
+		got := idx.Search(query, 1)
+		if len(got) == 1 && got[0] == bestID {
+			hits++
+		}
+	}
+	if hits < total*8/10 {
+		t.Fatalf("recall too low: %d/%d queries matched brute-force nearest neighbor", hits, total)
+	}
+}
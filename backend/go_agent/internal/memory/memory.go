@@ -6,15 +6,19 @@ import (
 	"math"
 	"os"
 	"path/filepath"
-	"sort"
 	"sync"
 	"time"
 
 	"yogz/go_agent/internal/config"
-	"yogz/go_agent/internal/ollama"
+	"yogz/go_agent/internal/llm"
 )
 
+// maxEntries caps how many entries Store retains; past this, the oldest
+// entries are evicted and the ANN index is rebuilt from what's left.
+const maxEntries = 500
+
 type Entry struct {
+	ID     int       `json:"id"`
 	Text   string    `json:"text"`
 	Vector []float64 `json:"vector"`
 	TS     time.Time `json:"ts"`
@@ -22,23 +26,40 @@ type Entry struct {
 	Kind   string    `json:"kind"`
 }
 
+// Store keeps a bounded history of embedded entries and answers nearest-
+// neighbor queries against them via an HNSW index (see hnsw.go). The index
+// itself isn't persisted -- it's cheap to rebuild from the entries at load
+// time, and that sidesteps keeping a separate graph file format in sync
+// with agent_memory_vectors.json.
 type Store struct {
-	cfg    config.Config
-	ollama *ollama.Client
+	cfg      config.Config
+	provider llm.Provider
+
 	mu     sync.Mutex
 	items  []Entry
-	path   string
+	nextID int
+	index  *hnswIndex
+
+	path string
 }
 
-func NewStore(cfg config.Config) *Store {
+// NewStore builds a Store that embeds through provider (the Agent's active
+// llm.Provider), so memory stays on whichever backend config.ProviderSpec
+// selected instead of hardcoding an Ollama client.
+func NewStore(cfg config.Config, provider llm.Provider) *Store {
 	path := filepath.Join(".", "agent_memory_vectors.json")
-	s := &Store{cfg: cfg, ollama: ollama.New(cfg.OllamaHost), path: path}
+	s := &Store{
+		cfg:      cfg,
+		provider: provider,
+		index:    newHNSWIndex(newHNSWConfig(cfg.HNSWM, cfg.HNSWEfConstruction, cfg.HNSWEfSearch)),
+		path:     path,
+	}
 	s.load()
 	return s
 }
 
 func (s *Store) Add(ctx context.Context, e Entry) error {
-	vec, err := s.ollama.Embed(ctx, s.cfg.EmbedModel, e.Text)
+	vec, err := s.provider.Embed(ctx, s.cfg.EmbedModel, e.Text)
 	if err != nil {
 		return err
 	}
@@ -46,13 +67,30 @@ func (s *Store) Add(ctx context.Context, e Entry) error {
 	e.TS = time.Now().UTC()
 
 	s.mu.Lock()
+	s.nextID++
+	e.ID = s.nextID
 	s.items = append(s.items, e)
-	// keep last N to limit growth
-	if len(s.items) > 500 {
-		s.items = s.items[len(s.items)-500:]
+	evicted := false
+	if len(s.items) > maxEntries {
+		s.items = s.items[len(s.items)-maxEntries:]
+		evicted = true
 	}
+	snapshot := append([]Entry(nil), s.items...)
+	idx := s.index
 	s.mu.Unlock()
 
+	if evicted {
+		// Oldest entries dropped out from under the index; HNSW has no cheap
+		// delete, so rebuild from what's left rather than leaving stale nodes.
+		s.rebuildIndex(snapshot)
+	} else {
+		// idx was read under s.mu, the same lock rebuildIndex takes to swap
+		// s.index out from under a concurrent caller -- hnswIndex guards its
+		// own graph with its own mutex, so Insert is safe to run against this
+		// local copy without holding s.mu for the whole call.
+		idx.Insert(e.ID, e.Vector)
+	}
+
 	s.save()
 	return nil
 }
@@ -61,35 +99,39 @@ func (s *Store) Query(ctx context.Context, q string, k int) []string {
 	if k <= 0 {
 		k = 4
 	}
-	qv, err := s.ollama.Embed(ctx, s.cfg.EmbedModel, q)
+	qv, err := s.provider.Embed(ctx, s.cfg.EmbedModel, q)
 	if err != nil {
 		return nil
 	}
+
 	s.mu.Lock()
-	items := append([]Entry(nil), s.items...)
+	byID := make(map[int]string, len(s.items))
+	for _, it := range s.items {
+		byID[it.ID] = it.Text
+	}
+	idx := s.index
 	s.mu.Unlock()
 
-	type scored struct {
-		t string
-		s float64
+	out := make([]string, 0, k)
+	for _, id := range idx.Search(qv, k) {
+		if t, ok := byID[id]; ok {
+			out = append(out, t)
+		}
 	}
-	sc := make([]scored, 0, len(items))
+	return out
+}
+
+func (s *Store) rebuildIndex(items []Entry) {
+	idx := newHNSWIndex(newHNSWConfig(s.cfg.HNSWM, s.cfg.HNSWEfConstruction, s.cfg.HNSWEfSearch))
 	for _, it := range items {
 		if len(it.Vector) == 0 {
 			continue
 		}
-		cs := cosine(qv, it.Vector)
-		sc = append(sc, scored{t: it.Text, s: cs})
-	}
-	sort.Slice(sc, func(i, j int) bool { return sc[i].s > sc[j].s })
-	if len(sc) > k {
-		sc = sc[:k]
+		idx.Insert(it.ID, it.Vector)
 	}
-	out := make([]string, 0, len(sc))
-	for _, x := range sc {
-		out = append(out, x.t)
-	}
-	return out
+	s.mu.Lock()
+	s.index = idx
+	s.mu.Unlock()
 }
 
 func cosine(a, b []float64) float64 {
@@ -106,7 +148,7 @@ func cosine(a, b []float64) float64 {
 		na += a[i] * a[i]
 		nb += b[i] * b[i]
 	}
-	den := math.Sqrt(na)*math.Sqrt(nb)
+	den := math.Sqrt(na) * math.Sqrt(nb)
 	if den == 0 {
 		return 0
 	}
@@ -122,7 +164,22 @@ func (s *Store) load() {
 	if err := json.Unmarshal(b, &items); err != nil {
 		return
 	}
+
+	maxID := 0
+	for i := range items {
+		// Entries persisted before IDs existed all read back as ID 0;
+		// assign them stable ids in arrival order rather than colliding.
+		if items[i].ID == 0 {
+			maxID++
+			items[i].ID = maxID
+		} else if items[i].ID > maxID {
+			maxID = items[i].ID
+		}
+	}
+
 	s.items = items
+	s.nextID = maxID
+	s.rebuildIndex(items)
 }
 
 func (s *Store) save() {
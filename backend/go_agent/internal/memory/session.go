@@ -0,0 +1,273 @@
+package memory
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Turn is one message in a Session's transcript.
+type Turn struct {
+	Role      string    `json:"role"`
+	Content   string    `json:"content"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Session is a first-class, ordered conversation on top of Store's flat
+// recall blob: Agent.Ask appends every question/answer pair to the caller's
+// active Session as well as calling Store.Add, so BuildPrompt can give the
+// model real multi-turn context (the last few Turns) in addition to
+// similarity-retrieved memory.
+type Session struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	Model     string    `json:"model"`
+	Turns     []Turn    `json:"turns"`
+}
+
+// SessionStore keeps every Session in memory and persists them as one JSON
+// file, same as Store does for recall entries -- sessions are small and
+// infrequent enough that a full rewrite per change is simpler than a log.
+type SessionStore struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	order    []string // insertion order, for stable List output
+	active   map[string]string
+
+	path string
+}
+
+// NewSessionStore loads persisted sessions from agent_sessions.json in the
+// working directory, creating an empty store if the file doesn't exist yet.
+func NewSessionStore() *SessionStore {
+	s := &SessionStore{
+		sessions: map[string]*Session{},
+		active:   map[string]string{},
+		path:     filepath.Join(".", "agent_sessions.json"),
+	}
+	s.load()
+	return s
+}
+
+// New starts a fresh Session for userID and makes it the active one, so the
+// next Ask/AskStream call appends to it instead of whatever was active
+// before.
+func (s *SessionStore) New(userID, model string) *Session {
+	sess := &Session{
+		ID:        newSessionID(),
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+		Model:     model,
+	}
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.order = append(s.order, sess.ID)
+	s.active[userID] = sess.ID
+	s.mu.Unlock()
+	s.save()
+	return sess
+}
+
+// Active returns userID's active Session, creating one if none exists yet --
+// so Agent.Ask can always append to a Session without every caller having to
+// remember to run "/session new" first.
+func (s *SessionStore) Active(userID, model string) *Session {
+	s.mu.Lock()
+	id, ok := s.active[userID]
+	if ok {
+		if sess, ok := s.sessions[id]; ok {
+			s.mu.Unlock()
+			return sess
+		}
+	}
+	s.mu.Unlock()
+	return s.New(userID, model)
+}
+
+// Get looks up a Session by ID regardless of owner, so "/session load" and
+// "/session replay" work across a user's own history.
+func (s *SessionStore) Get(id string) (*Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	return sess, ok
+}
+
+// List returns userID's sessions, oldest first.
+func (s *SessionStore) List(userID string) []*Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*Session, 0, len(s.order))
+	for _, id := range s.order {
+		if sess := s.sessions[id]; sess != nil && sess.UserID == userID {
+			out = append(out, sess)
+		}
+	}
+	return out
+}
+
+// SetActive makes session id the active one for userID, for "/session load".
+func (s *SessionStore) SetActive(userID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[id]
+	if !ok {
+		return fmt.Errorf("unknown session: %s", id)
+	}
+	if sess.UserID != userID {
+		return fmt.Errorf("session %s does not belong to this user", id)
+	}
+	s.active[userID] = id
+	return nil
+}
+
+// Fork clones userID's active session's turns into a brand-new session and
+// makes that the active one, so exploring an alternative prompt from here
+// doesn't lose (or mutate) the original history.
+func (s *SessionStore) Fork(userID, model string) *Session {
+	src := s.Active(userID, model)
+	s.mu.Lock()
+	turns := append([]Turn(nil), src.Turns...)
+	s.mu.Unlock()
+
+	sess := &Session{
+		ID:        newSessionID(),
+		UserID:    userID,
+		CreatedAt: time.Now().UTC(),
+		Model:     model,
+		Turns:     turns,
+	}
+	s.mu.Lock()
+	s.sessions[sess.ID] = sess
+	s.order = append(s.order, sess.ID)
+	s.active[userID] = sess.ID
+	s.mu.Unlock()
+	s.save()
+	return sess
+}
+
+// Append adds a turn to userID's active session, creating one if necessary.
+func (s *SessionStore) Append(userID, model string, t Turn) {
+	sess := s.Active(userID, model)
+	s.mu.Lock()
+	sess.Turns = append(sess.Turns, t)
+	s.mu.Unlock()
+	s.save()
+}
+
+// LastTurns returns the most recent n turns of userID's active session, for
+// BuildPrompt to fold into the model's context. It does not create a
+// session if none is active, so a fresh user sees an empty slice rather
+// than implicitly starting one.
+func (s *SessionStore) LastTurns(userID string, n int) []Turn {
+	if n <= 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id, ok := s.active[userID]
+	if !ok {
+		return nil
+	}
+	sess, ok := s.sessions[id]
+	if !ok || len(sess.Turns) == 0 {
+		return nil
+	}
+	turns := sess.Turns
+	if len(turns) > n {
+		turns = turns[len(turns)-n:]
+	}
+	return append([]Turn(nil), turns...)
+}
+
+// ExportJSON renders a session as indented JSON, for "/session save <path>"
+// when path does not end in ".md".
+func (s *SessionStore) ExportJSON(id string) ([]byte, error) {
+	sess, ok := s.Get(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown session: %s", id)
+	}
+	return json.MarshalIndent(sess, "", "  ")
+}
+
+// ExportMarkdown renders a session as a plain-text markdown transcript, for
+// sharing a reproduction of a bug report without a JSON viewer.
+func (s *SessionStore) ExportMarkdown(id string) (string, error) {
+	sess, ok := s.Get(id)
+	if !ok {
+		return "", fmt.Errorf("unknown session: %s", id)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Session %s\n\n", sess.ID)
+	fmt.Fprintf(&b, "- Created: %s\n", sess.CreatedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Model: %s\n\n", sess.Model)
+	for _, t := range sess.Turns {
+		fmt.Fprintf(&b, "**%s** (%s):\n\n%s\n\n", titleCase(t.Role), t.Timestamp.Format(time.RFC3339), t.Content)
+	}
+	return b.String(), nil
+}
+
+// titleCase upper-cases a role's first rune ("user" -> "User") for the
+// markdown transcript heading; roles are always short ASCII words so this
+// doesn't need the full unicode machinery strings.Title used to pull in.
+func titleCase(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+// Replay renders a session the same way ExportMarkdown does, for
+// "/session replay <id>" to print inline instead of writing a file.
+func (s *SessionStore) Replay(id string) (string, error) {
+	return s.ExportMarkdown(id)
+}
+
+func newSessionID() string {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "sess_" + strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return "sess_" + hex.EncodeToString(b[:])
+}
+
+func (s *SessionStore) load() {
+	b, err := os.ReadFile(s.path)
+	if err != nil {
+		return
+	}
+	var sessions []*Session
+	if err := json.Unmarshal(b, &sessions); err != nil {
+		return
+	}
+	for _, sess := range sessions {
+		s.sessions[sess.ID] = sess
+		s.order = append(s.order, sess.ID)
+	}
+	sort.SliceStable(s.order, func(i, j int) bool {
+		return s.sessions[s.order[i]].CreatedAt.Before(s.sessions[s.order[j]].CreatedAt)
+	})
+}
+
+func (s *SessionStore) save() {
+	s.mu.Lock()
+	out := make([]*Session, 0, len(s.order))
+	for _, id := range s.order {
+		out = append(out, s.sessions[id])
+	}
+	s.mu.Unlock()
+	b, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return
+	}
+	_ = os.WriteFile(s.path, b, 0600)
+}
@@ -12,6 +12,15 @@ import (
 	"time"
 )
 
+// streamIdleTimeout bounds how long GenerateStream will wait between
+// successive chunks before giving up on a stalled model. It's a sliding
+// deadline, not an overall one, so a model that's merely slow to produce
+// a long response isn't killed partway through.
+const streamIdleTimeout = 30 * time.Second
+
+// Client's HTTP client carries no blanket timeout; callers drive deadlines
+// through ctx, same as pyclient.Client, so a cancelled caller request
+// actually aborts the upstream call instead of waiting out a fixed timeout.
 type Client struct {
 	BaseURL string
 	HTTP    *http.Client
@@ -27,6 +36,18 @@ type GenerateResponse struct {
 	Response string `json:"response"`
 }
 
+// Token is one chunk of a streamed /api/generate response. Done marks the
+// final chunk, at which point EvalCount (if the server reported one) holds
+// the completion's token count. Error is set instead of Response when the
+// stream had to be aborted (idle timeout or decode failure); when the
+// caller's context ends the stream just closes, with no Error token.
+type Token struct {
+	Response  string `json:"response"`
+	Done      bool   `json:"done"`
+	EvalCount int    `json:"eval_count,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
 type EmbeddingsRequest struct {
 	Model  string `json:"model"`
 	Prompt string `json:"prompt"`
@@ -39,9 +60,7 @@ type EmbeddingsResponse struct {
 func New(baseURL string) *Client {
 	return &Client{
 		BaseURL: baseURL,
-		HTTP: &http.Client{
-			Timeout: 90 * time.Second,
-		},
+		HTTP:    &http.Client{},
 	}
 }
 
@@ -72,6 +91,107 @@ func (c *Client) Generate(ctx context.Context, model, prompt string) (string, er
 	return out.Response, nil
 }
 
+// GenerateStream starts a streaming /api/generate call and returns a
+// channel of Tokens as they arrive. The channel is closed once the server
+// sends a done chunk, the caller's context ends, or the stream stalls for
+// longer than streamIdleTimeout -- in the stall case (and on a decode
+// failure) a final Token carrying Error is sent first; a context that ends
+// closes the channel without one. Callers that abandon the channel before it
+// closes should cancel ctx to release the underlying connection.
+func (c *Client) GenerateStream(ctx context.Context, model, prompt string) (<-chan Token, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	body, _ := json.Marshal(GenerateRequest{Model: model, Prompt: prompt, Stream: true})
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/generate", bytes.NewReader(body))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		cancel()
+		msg := strings.TrimSpace(string(b))
+		if msg == "" {
+			return nil, fmt.Errorf("ollama generate failed (status=%d)", resp.StatusCode)
+		}
+		return nil, fmt.Errorf("ollama generate failed (status=%d): %s", resp.StatusCode, msg)
+	}
+
+	out := make(chan Token)
+	go streamTokens(ctx, cancel, resp.Body, out)
+	return out, nil
+}
+
+// streamTokens decodes newline-delimited JSON chunks from body and forwards
+// them on out. Decoding happens in its own goroutine so the select below
+// can enforce a sliding idle deadline: every chunk resets the timer, and
+// either the timer firing or ctx ending stops the loop and cancels ctx,
+// which unblocks the decode goroutine's in-flight read on body.
+func streamTokens(ctx context.Context, cancel context.CancelFunc, body io.ReadCloser, out chan<- Token) {
+	defer close(out)
+	defer cancel()
+	defer body.Close()
+
+	type result struct {
+		tok Token
+		err error
+	}
+	chunks := make(chan result, 1)
+	go func() {
+		dec := json.NewDecoder(body)
+		for {
+			var t Token
+			if err := dec.Decode(&t); err != nil {
+				chunks <- result{err: err}
+				return
+			}
+			chunks <- result{tok: t}
+			if t.Done {
+				return
+			}
+		}
+	}()
+
+	idle := time.NewTimer(streamIdleTimeout)
+	defer idle.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-idle.C:
+			out <- Token{Error: "ollama stream idle timeout exceeded"}
+			return
+		case r, ok := <-chunks:
+			if !ok {
+				return
+			}
+			if !idle.Stop() {
+				select {
+				case <-idle.C:
+				default:
+				}
+			}
+			idle.Reset(streamIdleTimeout)
+			if r.err != nil {
+				if r.err != io.EOF {
+					out <- Token{Error: r.err.Error()}
+				}
+				return
+			}
+			out <- r.tok
+			if r.tok.Done {
+				return
+			}
+		}
+	}
+}
+
 func (c *Client) Embed(ctx context.Context, model, text string) ([]float64, error) {
 	body, _ := json.Marshal(EmbeddingsRequest{Model: model, Prompt: text})
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+"/api/embeddings", bytes.NewReader(body))
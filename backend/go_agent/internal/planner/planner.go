@@ -0,0 +1,385 @@
+// Package planner implements the multi-step tool-calling loop shared by the
+// HTTP API and the CLI: send the question plus a tool manifest to the model,
+// parse a structured tool-call response, dispatch each call through the tool
+// registry, and feed the results back until the model returns a final
+// answer or an iteration budget is exhausted.
+package planner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"yogz/go_agent/internal/llm"
+	"yogz/go_agent/internal/tools"
+)
+
+// ToolCall is one invocation the model asked for in a single turn.
+type ToolCall struct {
+	Name      string         `json:"name"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// modelTurn is the structured response we expect back from the model: either
+// one or more tool calls to run, or a final answer.
+type modelTurn struct {
+	ToolCalls []ToolCall `json:"tool_calls"`
+	Answer    string     `json:"answer"`
+}
+
+// Step records one iteration of the loop for the caller-visible trace.
+type Step struct {
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	Results   []string   `json:"results,omitempty"`
+}
+
+// Generate sends a prompt to the underlying model and returns its raw text.
+type Generate func(ctx context.Context, prompt string) (string, error)
+
+// GenerateStream is Generate's streaming counterpart, for callers that want
+// to forward the model's output as it's produced instead of waiting for the
+// full turn.
+type GenerateStream func(ctx context.Context, prompt string) (<-chan llm.Token, error)
+
+// AuditFunc records a single tool invocation made during the loop.
+type AuditFunc func(ctx context.Context, tool string, args map[string]any, out string, err error)
+
+// Loop drives the plan/execute/observe cycle.
+type Loop struct {
+	Registry *tools.Registry
+	Runtime  *tools.Runtime
+	Generate Generate
+
+	// GenerateStream, if set, is used instead of Generate so a turn's raw
+	// output can be scanned for a live answer preview (see OnToken) as it
+	// streams in rather than only after the whole turn parses. The full
+	// turn is still accumulated and parsed exactly as Generate's result
+	// would be, so tool-calling behavior is unaffected either way.
+	GenerateStream GenerateStream
+
+	// OnToken, if set (and GenerateStream is too), is called with each new
+	// chunk of plain text recovered from an in-progress {"answer":"..."}
+	// turn -- e.g. the CLI prints it live instead of waiting for the turn
+	// to finish. It never fires for tool-calling turns, since their raw
+	// JSON isn't meaningful to show mid-stream.
+	OnToken func(delta string)
+
+	// MaxIterations bounds how many times we'll go back to the model.
+	// Defaults to 6 if unset.
+	MaxIterations int
+	// CallTimeout bounds each individual tool invocation. Defaults to 30s.
+	CallTimeout time.Duration
+	Audit       AuditFunc
+
+	// OnEvent, if set, is called with "tool_call" before each dispatch and
+	// "tool_result" after, so streaming callers (e.g. /v1/ask/stream) can
+	// forward progress inline instead of waiting for Run to return.
+	OnEvent func(kind string, payload any)
+
+	// Confirm, if set, is called before each tool call the model asks for;
+	// returning false skips dispatching that call and feeds the model a
+	// "declined by user" result instead. The CLI uses this to gate
+	// plan-produced /run commands that aren't on the configured run-policy
+	// allow-list behind an interactive y/N prompt -- the HTTP API leaves it
+	// unset since there's no terminal to prompt on.
+	Confirm func(ctx context.Context, call ToolCall) bool
+
+	// Authorize, if set, is checked before each tool call the model asks
+	// for (after Confirm); returning false fails that call with a
+	// scope-denied result instead of dispatching it. /v1/ask(/stream) sets
+	// this from the authenticated request's API key so a key scoped to
+	// "ask" alone can't reach "run", "write", etc. just by getting the
+	// model to ask for them -- auth.Middleware only ever gated the literal
+	// /v1/tools/run endpoint, not tool calls the loop dispatches on its
+	// own. The CLI leaves it unset since it isn't gated by API keys.
+	Authorize func(name string) bool
+}
+
+func (l *Loop) emit(kind string, payload any) {
+	if l.OnEvent != nil {
+		l.OnEvent(kind, payload)
+	}
+}
+
+// Run executes the loop for a single user question and returns the final
+// answer plus the step-by-step trace.
+func (l *Loop) Run(ctx context.Context, question string) (string, []Step, error) {
+	maxIter := l.MaxIterations
+	if maxIter <= 0 {
+		maxIter = 6
+	}
+	callTimeout := l.CallTimeout
+	if callTimeout <= 0 {
+		callTimeout = 30 * time.Second
+	}
+
+	manifest := l.Registry.Manifest()
+	manifestJSON, err := json.Marshal(manifest)
+	if err != nil {
+		return "", nil, fmt.Errorf("marshal tool manifest: %w", err)
+	}
+
+	var transcript strings.Builder
+	transcript.WriteString("You are an agent that can call tools to answer the user's question.\n")
+	transcript.WriteString("Available tools (JSON Schema parameters):\n")
+	transcript.Write(manifestJSON)
+	transcript.WriteString("\n\nRespond with exactly one JSON object per turn, either:\n")
+	transcript.WriteString(`  {"tool_calls":[{"name":"<tool>","arguments":{...}}]}` + "\n")
+	transcript.WriteString(`  {"answer":"<final answer text>"}` + "\n\n")
+	transcript.WriteString("User question: ")
+	transcript.WriteString(question)
+	transcript.WriteString("\n")
+
+	var trace []Step
+	for i := 0; i < maxIter; i++ {
+		raw, err := l.generateTurn(ctx, transcript.String())
+		if err != nil {
+			return "", trace, fmt.Errorf("model call failed: %w", err)
+		}
+		turn, ok := parseTurn(raw)
+		if !ok {
+			// Model didn't return structured JSON; treat its raw text as the
+			// final answer rather than erroring the whole request.
+			return strings.TrimSpace(raw), trace, nil
+		}
+		if len(turn.ToolCalls) == 0 {
+			return strings.TrimSpace(turn.Answer), trace, nil
+		}
+
+		step := Step{ToolCalls: turn.ToolCalls}
+		transcript.WriteString("\nAssistant tool_calls: ")
+		transcript.Write(mustJSON(turn.ToolCalls))
+
+		// A turn's tool calls are independent of each other (only the next
+		// turn depends on this one's results), so dispatch them concurrently
+		// instead of paying N sequential call-timeouts for one round-trip.
+		// Results are still folded into the transcript in the model's
+		// original order, so replaying a turn is deterministic regardless of
+		// which call happens to finish first.
+		results := make([]string, len(turn.ToolCalls))
+		var wg sync.WaitGroup
+		for _, call := range turn.ToolCalls {
+			l.emit("tool_call", call)
+		}
+		for i, call := range turn.ToolCalls {
+			wg.Add(1)
+			go func(i int, call ToolCall) {
+				defer wg.Done()
+				if l.Confirm != nil && !l.Confirm(ctx, call) {
+					out := "declined by user"
+					l.emit("tool_result", map[string]string{"name": call.Name, "output": out})
+					results[i] = out
+					return
+				}
+				if l.Authorize != nil && !l.Authorize(call.Name) {
+					out := fmt.Sprintf("tool %q not permitted: key lacks required scope", call.Name)
+					l.emit("tool_result", map[string]string{"name": call.Name, "output": out})
+					results[i] = out
+					return
+				}
+				out, err := l.dispatch(ctx, callTimeout, call)
+				if l.Audit != nil {
+					l.Audit(ctx, call.Name, call.Arguments, out, err)
+				}
+				if err != nil {
+					out = "error: " + err.Error()
+				}
+				l.emit("tool_result", map[string]string{"name": call.Name, "output": out})
+				results[i] = out
+			}(i, call)
+		}
+		wg.Wait()
+
+		for i, call := range turn.ToolCalls {
+			step.Results = append(step.Results, results[i])
+			transcript.WriteString("\nTOOL_RESULT ")
+			transcript.WriteString(call.Name)
+			transcript.WriteString(": ")
+			transcript.WriteString(results[i])
+		}
+		transcript.WriteString("\n")
+		trace = append(trace, step)
+	}
+	return "", trace, fmt.Errorf("planner loop exceeded max iterations (%d)", maxIter)
+}
+
+// generateTurn runs one model call, preferring GenerateStream (forwarding
+// live answer text through OnToken as it arrives) and falling back to the
+// plain blocking Generate when no streaming hook is configured.
+func (l *Loop) generateTurn(ctx context.Context, prompt string) (string, error) {
+	if l.GenerateStream == nil {
+		return l.Generate(ctx, prompt)
+	}
+	toks, err := l.GenerateStream(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	var raw strings.Builder
+	extractor := &answerExtractor{}
+	for tok := range toks {
+		if tok.Error != "" {
+			return "", fmt.Errorf("model stream: %s", tok.Error)
+		}
+		if tok.Response == "" {
+			continue
+		}
+		raw.WriteString(tok.Response)
+		if l.OnToken != nil {
+			if delta := extractor.feed(tok.Response); delta != "" {
+				l.OnToken(delta)
+			}
+		}
+	}
+	return raw.String(), nil
+}
+
+// answerExtractor incrementally recovers the plain-text contents of a
+// {"answer":"..."} turn from raw model output as it streams in, so a
+// caller can show the final answer as it's generated instead of waiting
+// for the whole JSON turn to parse. It gives up quietly for tool-calling
+// turns, whose raw JSON isn't meant to be shown mid-stream.
+type answerExtractor struct {
+	raw     strings.Builder
+	started bool
+	gaveUp  bool
+	done    bool
+	scanned int
+}
+
+const answerKeyMarker = `"answer":"`
+
+func (e *answerExtractor) feed(chunk string) string {
+	if e.done || e.gaveUp {
+		return ""
+	}
+	e.raw.WriteString(chunk)
+	buf := e.raw.String()
+	if !e.started {
+		if strings.Contains(buf, `"tool_calls"`) && !strings.Contains(buf, answerKeyMarker) {
+			e.gaveUp = true
+			return ""
+		}
+		idx := strings.Index(buf, answerKeyMarker)
+		if idx == -1 {
+			return ""
+		}
+		e.started = true
+		e.scanned = idx + len(answerKeyMarker)
+	}
+	var out strings.Builder
+	i := e.scanned
+	for i < len(buf) {
+		c := buf[i]
+		if c == '\\' {
+			if i+1 >= len(buf) {
+				break // wait for the rest of the escape sequence
+			}
+			switch buf[i+1] {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			default:
+				out.WriteByte(buf[i+1])
+			}
+			i += 2
+			continue
+		}
+		if c == '"' {
+			e.done = true
+			i++
+			break
+		}
+		out.WriteByte(c)
+		i++
+	}
+	e.scanned = i
+	return out.String()
+}
+
+func (l *Loop) dispatch(ctx context.Context, timeout time.Duration, call ToolCall) (string, error) {
+	cctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	args := flattenArgs(l.Registry, call.Name, call.Arguments)
+	return l.Registry.Run(cctx, l.Runtime, call.Name, args)
+}
+
+// flattenArgs converts a structured {name: value} argument map into the
+// positional []string args the Tool.Run signature expects, ordering by the
+// tool's declared schema (required properties first, then the rest
+// alphabetically) so callers don't need per-tool glue code.
+//
+// This is a straight one-property-per-element mapping, not a shell-word
+// split: a tool whose schema represents a whole command line as a single
+// string property (e.g. "run"'s "command") gets that string back as one
+// args element, not as separately tokenized words. Tool.Run implementations
+// that need the individual words -- RunTool deriving the executable to
+// check against its allow/deny list, for one -- must re-derive them from
+// the joined line themselves rather than assuming args is already
+// shell-split.
+func flattenArgs(reg *tools.Registry, name string, args map[string]any) []string {
+	t, ok := reg.Get(name)
+	if !ok || len(args) == 0 {
+		return nil
+	}
+	schema := t.Schema()
+	order := append([]string{}, schema.Required...)
+	seen := map[string]bool{}
+	for _, k := range order {
+		seen[k] = true
+	}
+	var rest []string
+	for k := range schema.Properties {
+		if !seen[k] {
+			rest = append(rest, k)
+		}
+	}
+	sort.Strings(rest)
+	order = append(order, rest...)
+
+	out := make([]string, 0, len(args))
+	for _, k := range order {
+		v, ok := args[k]
+		if !ok {
+			continue
+		}
+		out = append(out, fmt.Sprint(v))
+		delete(args, k)
+	}
+	// Any leftover keys not in the schema: append in a stable order.
+	var leftover []string
+	for k := range args {
+		leftover = append(leftover, k)
+	}
+	sort.Strings(leftover)
+	for _, k := range leftover {
+		out = append(out, fmt.Sprint(args[k]))
+	}
+	return out
+}
+
+func parseTurn(raw string) (modelTurn, bool) {
+	raw = strings.TrimSpace(raw)
+	start := strings.IndexByte(raw, '{')
+	end := strings.LastIndexByte(raw, '}')
+	if start == -1 || end == -1 || end < start {
+		return modelTurn{}, false
+	}
+	var turn modelTurn
+	if err := json.Unmarshal([]byte(raw[start:end+1]), &turn); err != nil {
+		return modelTurn{}, false
+	}
+	return turn, true
+}
+
+func mustJSON(v any) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte("null")
+	}
+	return b
+}
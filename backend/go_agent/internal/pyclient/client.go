@@ -0,0 +1,138 @@
+// Package pyclient is the shared HTTP client for talking to the companion
+// Python agent service. It exists so every caller -- the CLI's /py tool, the
+// API's ask planner -- honors the caller's context deadline instead of each
+// hardcoding its own timeout, and so a cancelled request actually aborts the
+// upstream call.
+package pyclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Client talks to a single Python agent service instance. Its HTTP client
+// carries no blanket timeout; callers drive deadlines through ctx so a
+// cancelled caller request aborts the upstream call and closes the
+// connection instead of leaking it until a fixed timeout fires.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+
+	// MaxRetries bounds how many times a transient network error is retried
+	// with jittered backoff before giving up. Defaults to 2.
+	MaxRetries int
+}
+
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTP:       &http.Client{},
+		MaxRetries: 2,
+	}
+}
+
+type AskRequest struct {
+	Question string `json:"question"`
+	UserID   string `json:"user_id"`
+	Model    string `json:"model,omitempty"`
+
+	// UseVenv asks the Python service to run the question through its
+	// venv-isolated entry point (see config.PythonAgentEntry) rather than
+	// its default interpreter. Set by the CLI's "/py --venv" flag.
+	UseVenv bool `json:"use_venv,omitempty"`
+}
+
+type AskResponse struct {
+	Answer string `json:"answer"`
+	TS     string `json:"ts"`
+}
+
+func (c *Client) Ask(ctx context.Context, req AskRequest) (AskResponse, error) {
+	var out AskResponse
+	body, err := json.Marshal(req)
+	if err != nil {
+		return out, err
+	}
+	err = c.doWithRetry(ctx, http.MethodPost, "/ask", body, &out)
+	return out, err
+}
+
+func (c *Client) Health(ctx context.Context) error {
+	return c.doWithRetry(ctx, http.MethodGet, "/status", nil, nil)
+}
+
+func (c *Client) doWithRetry(ctx context.Context, method, path string, body []byte, out any) error {
+	var lastErr error
+	retries := c.MaxRetries
+	if retries < 0 {
+		retries = 0
+	}
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			backoff := time.Duration(attempt) * 200 * time.Millisecond
+			jitter := time.Duration(rand.Int63n(int64(100 * time.Millisecond)))
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff + jitter):
+			}
+		}
+		err := c.do(ctx, method, path, body, out)
+		if err == nil {
+			return nil
+		}
+		if !isTransient(err) {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader *bytes.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("python service error: %d", resp.StatusCode)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// isTransient is a conservative guess at which errors are worth retrying:
+// anything that isn't a context cancellation/deadline, since those are
+// caller-driven and retrying them would just waste the remaining budget.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch err {
+	case context.Canceled, context.DeadlineExceeded:
+		return false
+	}
+	return true
+}
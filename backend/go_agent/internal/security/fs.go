@@ -2,6 +2,7 @@ package security
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
@@ -46,6 +47,114 @@ func hasPathPrefix(p, base string) bool {
 	return strings.HasPrefix(p, base)
 }
 
+// SafeFS resolves user-supplied paths against AllowDirs the way
+// IsPathAllowed does, but defends against symlink escapes: IsPathAllowed
+// only cleans the textual path, so a file inside an allowed directory
+// that is itself a symlink -- or sits under a symlinked ancestor --
+// pointing outside it would pass unnoticed. SafeFS instead resolves every
+// existing ancestor with filepath.EvalSymlinks and re-checks the
+// allowlist against the resolved path, so write/edit tools can't be
+// tricked into touching anything outside the sandbox.
+type SafeFS struct {
+	AllowDirs []string
+}
+
+// Resolve validates path for a read: the target must already exist, and
+// its fully resolved (symlinks followed) form must fall inside an
+// allowed directory.
+func (fs SafeFS) Resolve(path string) (string, error) {
+	real, exists, err := resolveReal(path)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("no such file or directory: %s", path)
+	}
+	if !fs.allowed(real) {
+		return "", errors.New("path not allowed")
+	}
+	return real, nil
+}
+
+// ResolveForWrite validates path for a write: its nearest existing ancestor,
+// with the missing components appended, must resolve inside an allowed
+// directory. It does not check that the parent directory exists. If the target
+// itself already exists as a symlink, the write is refused rather than
+// followed -- a symlink inside the sandbox pointing anywhere else is
+// exactly the escape SafeFS exists to close.
+func (fs SafeFS) ResolveForWrite(path string) (string, error) {
+	real, _, err := resolveReal(path)
+	if err != nil {
+		return "", err
+	}
+	if !fs.allowed(real) {
+		return "", errors.New("path not allowed")
+	}
+	// Lstat the path as given: real has already had its symlinks followed.
+	if info, err := os.Lstat(path); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", errors.New("refusing to write through a symlink")
+	}
+	return real, nil
+}
+
+func (fs SafeFS) allowed(real string) bool {
+	for _, d := range fs.AllowDirs {
+		d = strings.TrimSpace(d)
+		if d == "" {
+			continue
+		}
+		base, err := filepath.Abs(d)
+		if err != nil {
+			continue
+		}
+		base = filepath.Clean(base)
+		if resolved, err := filepath.EvalSymlinks(base); err == nil {
+			base = resolved
+		}
+		if hasPathPrefix(real, base) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveReal resolves path to an absolute form with every existing
+// ancestor's symlinks followed, reporting whether the path itself exists.
+// Non-existent trailing components (the file a WriteTool is about to
+// create) are appended untouched once the real, existing prefix is found.
+func resolveReal(path string) (real string, exists bool, err error) {
+	if path == "" {
+		return "", false, errors.New("empty path")
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false, err
+	}
+	abs = filepath.Clean(abs)
+
+	dir := abs
+	var suffix []string
+	for {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false, fmt.Errorf("no existing ancestor for %s", path)
+		}
+		suffix = append([]string{filepath.Base(dir)}, suffix...)
+		dir = parent
+	}
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return "", false, err
+	}
+	if len(suffix) == 0 {
+		return resolved, true, nil
+	}
+	return filepath.Join(append([]string{resolved}, suffix...)...), false, nil
+}
+
 func ReadFileLimited(path string, maxBytes int64) ([]byte, error) {
 	f, err := os.Open(path)
 	if err != nil {
@@ -2,23 +2,26 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
-	"os/exec"
-	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"yogz/go_agent/internal/agent"
+	"yogz/go_agent/internal/audit"
 	"yogz/go_agent/internal/auth"
 	"yogz/go_agent/internal/config"
 	"yogz/go_agent/internal/limits"
+	"yogz/go_agent/internal/planner"
 	"yogz/go_agent/internal/security"
-	"yogz/go_agent/internal/tools"
-	"yogz/go_agent/internal/audit"
 	"yogz/go_agent/internal/tokens"
+	"yogz/go_agent/internal/tools"
 )
 
 type Server struct {
@@ -35,13 +38,22 @@ type askRequest struct {
 }
 
 type askResponse struct {
-	Answer string `json:"answer"`
-	TS     string `json:"ts"`
+	Answer string         `json:"answer"`
+	TS     string         `json:"ts"`
+	Steps  []planner.Step `json:"steps,omitempty"`
 }
 
 func (s *Server) Run(ctx context.Context, addr string) error {
 	mux := http.NewServeMux()
-	aud := audit.New(s.cfg.AuditLogPath)
+	aud, err := audit.NewFromConfig(s.cfg)
+	if err != nil {
+		return fmt.Errorf("open audit log: %w", err)
+	}
+
+	store, err := buildKeyStore(s.cfg)
+	if err != nil {
+		return fmt.Errorf("load auth keys: %w", err)
+	}
 
 	reg := tools.NewRegistry()
 	help := &tools.HelpTool{Reg: reg}
@@ -49,12 +61,37 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 	reg.Register(&tools.StatusTool{})
 	reg.Register(&tools.ModelTool{})
 	reg.Register(&tools.LSTool{})
+	reg.Register(&tools.CatTool{})
+	reg.Register(&tools.WriteTool{})
+	reg.Register(&tools.PatchTool{})
+	reg.Register(&tools.GrepTool{})
 	reg.Register(&tools.PythonTool{})
 
+	// Every Run through reg -- direct /v1/tools/run calls and planner tool
+	// dispatch alike -- gets a uniform audit record, independent of the
+	// planner's own higher-level "tool_call" events.
+	reg.Audit = func(ctx context.Context, name string, args []string, out string, dur time.Duration, err error) {
+		note := ""
+		if err != nil {
+			note = err.Error()
+		}
+		aud.Write(audit.Event{
+			Kind:       "tool_run",
+			RequestID:  requestIDFromContext(ctx),
+			UserID:     actorFromContext(ctx),
+			Tool:       name,
+			Args:       strings.Join(args, " "),
+			Result:     tokens.ClampChars(out, 500),
+			DurationMs: dur.Milliseconds(),
+			Note:       note,
+		})
+	}
+
 	rt := &tools.Runtime{
-		GetStatus: func() map[string]any { return s.ag.Status() },
-		GetModel:  func() string { return s.ag.GetModel() },
-		SetModel:  func(m string) { s.ag.SetModel(m) },
+		GetStatus:  func() map[string]any { return s.ag.Status() },
+		GetModel:   func() string { return s.ag.GetModel() },
+		SetModel:   func(m string) { s.ag.SetModel(m) },
+		ListModels: func() []string { return s.ag.Models() },
 		EnableFS: func() bool {
 			ok, _ := s.ag.FSSettings()
 			return ok
@@ -63,24 +100,26 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 			_, dirs := s.ag.FSSettings()
 			return dirs
 		}(),
+		MaxWriteBytes:  s.cfg.MaxWriteBytes,
+		RunPolicy:      tools.NewRunPolicy(s.cfg),
 		PythonToolsURL: strings.TrimRight(s.cfg.PythonToolsURL, "/"),
 	}
 
 	mux.HandleFunc("/v1/status", func(w http.ResponseWriter, r *http.Request) {
 		writeJSON(w, map[string]any{
-			"status":             "ok",
-			"model":              s.ag.GetModel(),
+			"status":              "ok",
+			"model":               s.ag.GetModel(),
 			"ollama_host":         s.cfg.OllamaHost,
 			"max_input_chars":     s.cfg.MaxInputChars,
 			"max_output_chars":    s.cfg.MaxOutputChars,
 			"rate_limit_per_min":  s.cfg.RateLimitPerMin,
-			"auth_required":       len(s.cfg.APIKeys) > 0,
+			"auth_required":       len(s.cfg.APIKeys) > 0 || s.cfg.AuthKeysFile != "",
 			"fs_enabled":          s.cfg.EnableFS,
 			"allowed_directories": s.cfg.AllowDirs,
 		})
 	})
 
-	mux.Handle("/v1/tools/list", auth.Middleware(s.cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/v1/tools/list", auth.Middleware(store, "tools:run", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
 			writeErrJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
 			return
@@ -88,11 +127,19 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 		writeJSON(w, map[string]any{"tools": reg.Names()})
 	})))
 
+	mux.Handle("/v1/tools/schema", auth.Middleware(store, "tools:run", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeErrJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+			return
+		}
+		writeJSON(w, map[string]any{"tools": reg.Manifest()})
+	})))
+
 	type runToolRequest struct {
 		Name string   `json:"name"`
 		Args []string `json:"args"`
 	}
-	mux.Handle("/v1/tools/run", auth.Middleware(s.cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/v1/tools/run", auth.Middleware(store, "tools:run", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeErrJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
 			return
@@ -107,6 +154,12 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 			writeErrJSON(w, http.StatusBadRequest, "missing_name", "missing tool name")
 			return
 		}
+		if name == "py" {
+			if key, ok := auth.KeyFromContext(r.Context()); ok && !key.HasScope("tools:run:py") {
+				writeErrJSON(w, http.StatusForbidden, "forbidden", "key lacks required scope: tools:run:py")
+				return
+			}
+		}
 		out, err := reg.Run(r.Context(), rt, name, req.Args)
 		if err != nil {
 			writeErrJSON(w, http.StatusBadRequest, "tool_error", err.Error())
@@ -115,7 +168,7 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 		writeJSON(w, map[string]any{"name": name, "output": out})
 	})))
 
-	mux.Handle("/v1/ask", auth.Middleware(s.cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/v1/ask", auth.Middleware(store, "ask", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			writeErrJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
 			return
@@ -131,36 +184,145 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 			return
 		}
 		userID := auth.UserIDFromRequest(r)
-		// Forward to Python agent service
-		pyURL := strings.TrimRight(s.cfg.PythonToolsURL, "/") + "/ask"
-		payload := map[string]any{"question": q, "user_id": userID}
-		bodyBytes, _ := json.Marshal(payload)
-		resp, err := http.Post(pyURL, "application/json", strings.NewReader(string(bodyBytes)))
+
+		loop := &planner.Loop{
+			Registry:      reg,
+			Runtime:       rt,
+			Generate:      s.ag.Generate,
+			MaxIterations: 6,
+			CallTimeout:   20 * time.Second,
+			Audit: func(ctx context.Context, tool string, args map[string]any, out string, err error) {
+				note := ""
+				if err != nil {
+					note = err.Error()
+				}
+				aud.Write(audit.Event{
+					Kind:      "tool_call",
+					RequestID: requestIDFromContext(ctx),
+					UserID:    userID,
+					Tool:      tool,
+					Note:      note,
+				})
+			},
+			Authorize: authorizeTool(r),
+		}
+		answer, steps, err := loop.Run(r.Context(), q)
 		if err != nil {
-			writeErrJSON(w, http.StatusBadGateway, "python_service_unavailable", "python agent service unavailable")
+			writeErrJSON(w, http.StatusBadGateway, "planner_error", err.Error())
+			return
+		}
+		writeJSON(w, askResponse{
+			Answer: tokens.ClampChars(answer, s.cfg.MaxOutputChars),
+			TS:     time.Now().UTC().Format(time.RFC3339),
+			Steps:  steps,
+		})
+	})))
+
+	mux.Handle("/v1/ask/stream", auth.Middleware(store, "ask", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeErrJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "use POST")
 			return
 		}
-		defer resp.Body.Close()
-		if resp.StatusCode != http.StatusOK {
-			writeErrJSON(w, http.StatusBadGateway, "python_service_error", "python agent service error")
+		var req askRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeErrJSON(w, http.StatusBadRequest, "invalid_json", "invalid json")
 			return
 		}
-		var pyResp askResponse
-		if err := json.NewDecoder(resp.Body).Decode(&pyResp); err != nil {
-			writeErrJSON(w, http.StatusInternalServerError, "invalid_python_response", "invalid response from python service")
+		q := tokens.ClampChars(req.Question, s.cfg.MaxInputChars)
+		if q == "" {
+			writeErrJSON(w, http.StatusBadRequest, "missing_question", "missing question")
 			return
 		}
-		// If Python returned a JSON plan, execute it and return the result
-		if strings.HasPrefix(strings.TrimSpace(pyResp.Answer), "{") {
-			if planOut, err := executeJSONPlanAPI(r.Context(), s, pyResp.Answer); err == nil && planOut != "" {
-				writeJSON(w, askResponse{Answer: tokens.ClampChars(planOut, s.cfg.MaxOutputChars), TS: pyResp.TS})
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeErrJSON(w, http.StatusInternalServerError, "streaming_unsupported", "response writer does not support flushing")
+			return
+		}
+		userID := auth.UserIDFromRequest(r)
+
+		lastSeen := 0
+		if v := r.Header.Get("Last-Event-ID"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil {
+				lastSeen = n
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		var mu sync.Mutex
+		eventID := 0
+		writeEvent := func(kind string, payload any) {
+			mu.Lock()
+			defer mu.Unlock()
+			eventID++
+			if eventID <= lastSeen {
+				// Already delivered before a reconnect; skip but keep the
+				// counter moving so ids stay consistent across retries.
+				return
+			}
+			b, err := json.Marshal(payload)
+			if err != nil {
+				b = []byte(`{}`)
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", eventID, kind, b)
+			flusher.Flush()
+		}
+
+		loop := &planner.Loop{
+			Registry:      reg,
+			Runtime:       rt,
+			Generate:      s.ag.Generate,
+			MaxIterations: 6,
+			CallTimeout:   20 * time.Second,
+			Audit: func(ctx context.Context, tool string, args map[string]any, out string, err error) {
+				note := ""
+				if err != nil {
+					note = err.Error()
+				}
+				aud.Write(audit.Event{
+					Kind:      "tool_call",
+					RequestID: requestIDFromContext(ctx),
+					UserID:    userID,
+					Tool:      tool,
+					Note:      note,
+				})
+			},
+			OnEvent:   writeEvent,
+			Authorize: authorizeTool(r),
+		}
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			answer, _, err := loop.Run(r.Context(), q)
+			if err != nil {
+				writeEvent("error", map[string]string{"message": err.Error()})
 				return
 			}
+			writeEvent("final", map[string]string{"answer": tokens.ClampChars(answer, s.cfg.MaxOutputChars)})
+		}()
+
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-done:
+				return
+			case <-heartbeat.C:
+				mu.Lock()
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+				mu.Unlock()
+			}
 		}
-		writeJSON(w, askResponse{Answer: tokens.ClampChars(pyResp.Answer, s.cfg.MaxOutputChars), TS: pyResp.TS})
 	})))
 
-	mux.Handle("/v1/fs/read", auth.Middleware(s.cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/v1/fs/read", auth.Middleware(store, "fs:read", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if !s.cfg.EnableFS {
 			writeErrJSON(w, http.StatusForbidden, "fs_disabled", "filesystem disabled")
 			return
@@ -189,17 +351,87 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 		writeJSON(w, map[string]any{"path": abs, "content": string(b)})
 	})))
 
-	lim := limits.NewKeyedLimiter(s.cfg.RateLimitPerMin)
+	mux.Handle("/v1/audit/tail", auth.Middleware(store, "audit:read", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeErrJSON(w, http.StatusMethodNotAllowed, "method_not_allowed", "use GET")
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeErrJSON(w, http.StatusInternalServerError, "streaming_unsupported", "response writer does not support flushing")
+			return
+		}
+		kind := r.URL.Query().Get("kind")
+		var since uint64
+		if v := r.URL.Query().Get("since"); v != "" {
+			since, _ = strconv.ParseUint(v, 10, 64)
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+
+		writeAuditEvent := func(e audit.Event) {
+			b, err := json.Marshal(e)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "id: %d\nevent: audit\ndata: %s\n\n", e.Seq, b)
+			flusher.Flush()
+		}
+		for _, e := range aud.Since(since, kind) {
+			writeAuditEvent(e)
+		}
+
+		sub := aud.Subscribe()
+		defer aud.Unsubscribe(sub)
+		heartbeat := time.NewTicker(15 * time.Second)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-sub:
+				if !ok {
+					return
+				}
+				if kind != "" && e.Kind != kind {
+					continue
+				}
+				writeAuditEvent(e)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+			}
+		}
+	})))
+
+	// /v1/ask(/stream) drive an expensive planner loop, so they get a
+	// stricter tier than the configured default; /v1/status is a cheap
+	// health check that can tolerate a much looser one.
+	lim := limits.NewTieredLimiter(limits.Tier{PerMin: s.cfg.RateLimitPerMin}, map[string]limits.Tier{
+		"/v1/ask":        {PerMin: s.cfg.RateLimitPerMin / 2},
+		"/v1/ask/stream": {PerMin: s.cfg.RateLimitPerMin / 2},
+		"/v1/status":     {PerMin: s.cfg.RateLimitPerMin * 4},
+	})
 	h := lim.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reqID := newRequestID()
+		start := time.Now()
+		r = r.WithContext(withRequestID(r.Context(), reqID))
 		rw := &statusWriter{ResponseWriter: w, status: 200}
 		mux.ServeHTTP(rw, r)
 		aud.Write(audit.Event{
-			Kind:   "http",
-			UserID: auth.UserIDFromRequest(r),
-			IP:     clientIP(r),
-			Method: r.Method,
-			Path:   r.URL.Path,
-			Status: rw.status,
+			Kind:       "http",
+			RequestID:  reqID,
+			UserID:     auth.UserIDFromRequest(r),
+			IP:         clientIP(r),
+			Method:     r.Method,
+			Path:       r.URL.Path,
+			Status:     rw.status,
+			DurationMs: time.Since(start).Milliseconds(),
+			BytesIn:    r.ContentLength,
+			BytesOut:   rw.bytes,
 		})
 	}))
 
@@ -212,13 +444,37 @@ func (s *Server) Run(ctx context.Context, addr string) error {
 	}()
 
 	log.Println("listening on", addr)
-	err := srv.ListenAndServe()
+	err = srv.ListenAndServe()
 	if err == http.ErrServerClosed {
 		return nil
 	}
 	return err
 }
 
+// buildKeyStore resolves the configured auth backend: a hot-reloading file
+// store takes precedence if set, otherwise inline AGENT_API_KEYS specs are
+// parsed into an in-memory store. With neither configured it returns a nil
+// store, which auth.Middleware treats as open access.
+func buildKeyStore(cfg config.Config) (auth.KeyStore, error) {
+	if cfg.AuthKeysFile != "" {
+		return auth.NewFileKeyStore(cfg.AuthKeysFile)
+	}
+	if len(cfg.APIKeys) > 0 {
+		return auth.ParseKeySpecs(cfg.APIKeys)
+	}
+	return nil, nil
+}
+
+// newRequestID returns a short random hex identifier for correlating audit
+// events across a single request's lifetime.
+func newRequestID() string {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b[:])
+}
+
 func writeJSON(w http.ResponseWriter, v any) {
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(v)
@@ -230,58 +486,10 @@ func writeErrJSON(w http.ResponseWriter, code int, kind, msg string) {
 	_ = json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"kind": kind, "message": msg}})
 }
 
-func executeJSONPlanAPI(ctx context.Context, s *Server, jsonStr string) (string, error) {
-	var plan struct {
-		Action  string `json:"action"`
-		Command string `json:"command"`
-	}
-	if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
-		return "", fmt.Errorf("invalid plan JSON")
-	}
-	switch plan.Action {
-	case "run":
-		if plan.Command == "" {
-			return "", fmt.Errorf("run plan missing command")
-		}
-		cmd := normalizeCommandAPI(plan.Command)
-		var execCmd *exec.Cmd
-		if runtime.GOOS == "windows" {
-			execCmd = exec.CommandContext(ctx, "powershell", "-Command", cmd)
-		} else {
-			execCmd = exec.CommandContext(ctx, "sh", "-c", cmd)
-		}
-		out, err := execCmd.CombinedOutput()
-		if err != nil {
-			return fmt.Sprintf("Error: %v\nOutput: %s", err, out), nil
-		}
-		return string(out), nil
-	default:
-		return "", fmt.Errorf("unsupported plan action: %s", plan.Action)
-	}
-}
-
-func normalizeCommandAPI(cmd string) string {
-	// Simple heuristic: if it looks like a Python heredoc, convert to -c
-	if strings.Contains(cmd, "<<'PY'") {
-		start := strings.Index(cmd, "<<'PY'")
-		if start == -1 {
-			return cmd
-		}
-		start += len("<<'PY'")
-		end := strings.Index(cmd[start:], "PY")
-		if end == -1 {
-			return cmd
-		}
-		code := strings.TrimSpace(cmd[start : start+end])
-		code = strings.ReplaceAll(code, `"`, `\"`)
-		return fmt.Sprintf("python -c \"%s\"", code)
-	}
-	return cmd
-}
-
 type statusWriter struct {
 	http.ResponseWriter
 	status int
+	bytes  int64
 }
 
 func (w *statusWriter) WriteHeader(code int) {
@@ -289,6 +497,67 @@ func (w *statusWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Flush forwards to the underlying writer, so the SSE handlers' http.Flusher
+// check still succeeds behind this wrapper.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += int64(n)
+	return n, err
+}
+
+// requestIDKey is the context key used to thread the per-request audit ID
+// from the outer logging wrapper down to handlers that emit their own
+// audit events (e.g. tool calls during /v1/ask).
+type requestIDKey struct{}
+
+func withRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// actorFromContext returns the API key ID behind ctx's request, if
+// auth.Middleware attached one. Tool runs triggered outside of an HTTP
+// request (e.g. the CLI's direct Registry.Run calls) have no key in
+// context and audit as an empty actor.
+func actorFromContext(ctx context.Context) string {
+	if key, ok := auth.KeyFromContext(ctx); ok {
+		return key.ID
+	}
+	return ""
+}
+
+// authorizeTool builds a planner.Loop.Authorize func from r's authenticated
+// API key, so /v1/ask(/stream) enforces the same "tools:run" (and, for py,
+// "tools:run:py") scopes on model-issued tool calls that /v1/tools/run
+// already enforces on direct ones -- "ask" alone isn't enough to reach a
+// tool through the planner loop. No key in context (auth.Middleware wasn't
+// configured with a store) means open access, same as everywhere else.
+func authorizeTool(r *http.Request) func(name string) bool {
+	return func(name string) bool {
+		key, ok := auth.KeyFromContext(r.Context())
+		if !ok {
+			return true
+		}
+		if !key.HasScope("tools:run") {
+			return false
+		}
+		if name == "py" && !key.HasScope("tools:run:py") {
+			return false
+		}
+		return true
+	}
+}
+
 func clientIP(r *http.Request) string {
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
 		parts := strings.Split(xff, ",")
@@ -0,0 +1,33 @@
+//go:build !windows
+
+package supervisor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup puts the child in a new process group so Stop can signal
+// the whole tree (interpreter plus anything it forks) instead of just the
+// immediate child.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// killProcessTree signals the child's process group rather than just the
+// child, so grandchildren it spawned die with it.
+func killProcessTree(cmd *exec.Cmd) {
+	pgid, err := syscall.Getpgid(cmd.Process.Pid)
+	if err != nil {
+		_ = cmd.Process.Kill()
+		return
+	}
+	_ = syscall.Kill(-pgid, syscall.SIGTERM)
+}
+
+// assignToJob is a no-op on Unix; setpgid at Start time already does the
+// job-object equivalent of grouping the tree for termination.
+func assignToJob(pid int) {}
@@ -0,0 +1,122 @@
+//go:build windows
+
+package supervisor
+
+import (
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	modkernel32                   = syscall.NewLazyDLL("kernel32.dll")
+	procCreateJobObjectW          = modkernel32.NewProc("CreateJobObjectW")
+	procSetInformationJobObject   = modkernel32.NewProc("SetInformationJobObject")
+	procAssignProcessToJobObject  = modkernel32.NewProc("AssignProcessToJobObject")
+	procTerminateJobObject        = modkernel32.NewProc("TerminateJobObject")
+)
+
+const (
+	jobObjectExtendedLimitInformation = 9
+	jobObjectLimitKillOnJobClose      = 0x2000
+)
+
+// jobObjectBasicLimitInformation mirrors the Win32
+// JOBOBJECT_BASIC_LIMIT_INFORMATION struct, embedded in
+// jobObjectExtendedLimitInfo to set JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE.
+type jobObjectBasicLimitInformation struct {
+	PerProcessUserTimeLimit int64
+	PerJobUserTimeLimit     int64
+	LimitFlags              uint32
+	MinimumWorkingSetSize   uintptr
+	MaximumWorkingSetSize   uintptr
+	ActiveProcessLimit      uint32
+	Affinity                uintptr
+	PriorityClass           uint32
+	SchedulingClass         uint32
+}
+
+type ioCounters struct {
+	ReadOperationCount  uint64
+	WriteOperationCount uint64
+	OtherOperationCount uint64
+	ReadTransferCount   uint64
+	WriteTransferCount  uint64
+	OtherTransferCount  uint64
+}
+
+type jobObjectExtendedLimitInfo struct {
+	BasicLimitInformation jobObjectBasicLimitInformation
+	IoInfo                ioCounters
+	ProcessMemoryLimit    uintptr
+	JobMemoryLimit        uintptr
+	PeakProcessMemoryUsed uintptr
+	PeakJobMemoryUsed     uintptr
+}
+
+// jobHandle is a handle to the Windows job object the child was assigned to, so
+// Stop can kill the whole tree in one call instead of chasing PIDs.
+var jobHandle syscall.Handle
+
+// setProcessGroup puts the child in its own console process group
+// (CREATE_NEW_PROCESS_GROUP) and assigns it to a job object configured to
+// kill all member processes when the job handle is closed, so Stop tears
+// down the full tree rather than leaving grandchildren behind.
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+
+	// reviveLoop calls setProcessGroup on every restart; killProcessTree
+	// already closes jobHandle when it tears a tree down itself, but a
+	// child that exits on its own (crash, normal exit) leaves jobHandle set
+	// with nothing left to terminate. Close it here before the handle gets
+	// overwritten, or every crash-restart leaks a job object.
+	if jobHandle != 0 {
+		_ = syscall.CloseHandle(jobHandle)
+		jobHandle = 0
+	}
+
+	h, _, _ := procCreateJobObjectW.Call(0, 0)
+	if h == 0 {
+		return
+	}
+	jobHandle = syscall.Handle(h)
+
+	info := jobObjectExtendedLimitInfo{
+		BasicLimitInformation: jobObjectBasicLimitInformation{
+			LimitFlags: jobObjectLimitKillOnJobClose,
+		},
+	}
+	_, _, _ = procSetInformationJobObject.Call(
+		uintptr(jobHandle),
+		jobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)),
+		unsafe.Sizeof(info),
+	)
+}
+
+// killProcessTree terminates the job object (killing every process it
+// owns) if one was created, falling back to killing just the direct child.
+func killProcessTree(cmd *exec.Cmd) {
+	if jobHandle != 0 {
+		_, _, _ = procTerminateJobObject.Call(uintptr(jobHandle), 1)
+		_ = syscall.CloseHandle(jobHandle)
+		jobHandle = 0
+		return
+	}
+	_ = cmd.Process.Kill()
+}
+
+func assignToJob(pid int) {
+	if jobHandle == 0 {
+		return
+	}
+	h, err := syscall.OpenProcess(syscall.PROCESS_ALL_ACCESS, false, uint32(pid))
+	if err != nil {
+		return
+	}
+	defer syscall.CloseHandle(h)
+	_, _, _ = procAssignProcessToJobObject.Call(uintptr(jobHandle), uintptr(h))
+}
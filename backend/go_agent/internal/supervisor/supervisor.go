@@ -1,9 +1,16 @@
+// Package supervisor manages the lifecycle of the companion Python agent
+// service: locating an interpreter, provisioning a private virtualenv,
+// starting the child in its own process group so the whole tree can be torn
+// down together, and restarting it with backoff if it crashes.
 package supervisor
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"os"
@@ -11,86 +18,286 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	"yogz/go_agent/internal/audit"
 	"yogz/go_agent/internal/config"
 )
 
+// Result is what callers get back from EnsurePythonService: where the
+// service is reachable, and how to stop and inspect it.
 type Result struct {
 	PythonURL string
 	Stop      func()
+
+	pid     func() int
+	healthy func() bool
+}
+
+// PID returns the current child process ID, or 0 if the service isn't
+// managed by us (e.g. the caller pointed at an already-running instance).
+func (r Result) PID() int {
+	if r.pid == nil {
+		return 0
+	}
+	return r.pid()
+}
+
+// Healthy reports whether the service is currently responding and the
+// restart circuit breaker hasn't tripped.
+func (r Result) Healthy() bool {
+	if r.healthy == nil {
+		return false
+	}
+	return r.healthy()
 }
 
+const (
+	restartWindow       = 2 * time.Minute
+	maxFailuresInWindow = 5
+	initialBackoff      = 1 * time.Second
+	maxBackoff          = 30 * time.Second
+)
+
 func EnsurePythonService(ctx context.Context, cfg config.Config) (Result, error) {
-	// If user explicitly configured a URL and it's already healthy, use it.
+	// If the caller already pointed us at a healthy instance, use it as-is;
+	// we don't manage processes we didn't start.
 	if strings.TrimSpace(cfg.PythonToolsURL) != "" {
-		if isHealthy(ctx, strings.TrimRight(cfg.PythonToolsURL, "/")) {
-			return Result{PythonURL: strings.TrimRight(cfg.PythonToolsURL, "/"), Stop: func() {}}, nil
+		url := strings.TrimRight(cfg.PythonToolsURL, "/")
+		if isHealthy(ctx, url) {
+			return Result{PythonURL: url, Stop: func() {}, healthy: func() bool { return isHealthy(ctx, url) }}, nil
 		}
 	}
 
-	if runtime.GOOS != "windows" {
-		return Result{}, fmt.Errorf("supervisor currently supports windows only")
-	}
-
 	entry, err := resolvePythonAgentEntry(cfg.PythonAgentEntry)
 	if err != nil {
 		return Result{}, err
 	}
 
-	port, err := freeLocalPort()
+	pythonBin, baseArgs, err := findPythonInterpreter()
 	if err != nil {
 		return Result{}, err
 	}
-	pythonURL := fmt.Sprintf("http://127.0.0.1:%d", port)
 
-	logFile, err := openLogFile("python_agent.log")
+	dataDir, err := appDataDir()
 	if err != nil {
 		return Result{}, err
 	}
-	// closed in Stop
+	venvDir := filepath.Join(dataDir, "venv")
+	if err := os.MkdirAll(dataDir, 0o755); err != nil {
+		return Result{}, err
+	}
+
+	aud := audit.New(filepath.Join(dataDir, "python_agent_audit.log"))
 
-	cmd := exec.CommandContext(ctx, "python", entry)
-	cmd.Env = append(os.Environ(),
-		"PYTHONUNBUFFERED=1",
-		"PYTHON_AGENT_HOST=127.0.0.1",
-		fmt.Sprintf("PYTHON_AGENT_PORT=%d", port),
-	)
-	cmd.Stdout = logFile
-	cmd.Stderr = logFile
+	if err := ensureVenv(ctx, pythonBin, baseArgs, venvDir); err != nil {
+		return Result{}, fmt.Errorf("provision venv: %w", err)
+	}
+	reqPath := filepath.Join(filepath.Dir(entry), "requirements.txt")
+	if err := ensureDeps(ctx, venvDir, reqPath); err != nil {
+		return Result{}, fmt.Errorf("install python dependencies: %w", err)
+	}
 
-	if err := cmd.Start(); err != nil {
-		_ = logFile.Close()
+	port, err := freeLocalPort()
+	if err != nil {
 		return Result{}, err
 	}
+	pythonURL := fmt.Sprintf("http://127.0.0.1:%d", port)
+
+	sv := &supervisorProc{
+		venvPython: venvPython(venvDir),
+		entry:      entry,
+		port:       port,
+		aud:        aud,
+	}
 
-	exited := make(chan error, 1)
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
 	go func() {
-		exited <- cmd.Wait()
+		defer close(done)
+		sv.reviveLoop(runCtx)
 	}()
 
+	if !waitHealthy(runCtx, pythonURL, 60*time.Second) {
+		cancel()
+		<-done
+		return Result{}, fmt.Errorf("python agent service failed to become healthy")
+	}
+
 	stop := func() {
-		_ = cmd.Process.Kill()
+		cancel()
+		sv.killCurrent()
+		<-done
+	}
+
+	return Result{
+		PythonURL: pythonURL,
+		Stop:      stop,
+		pid:       sv.currentPID,
+		healthy:   func() bool { return !sv.circuitOpen() && isHealthy(runCtx, pythonURL) },
+	}, nil
+}
+
+// supervisorProc owns the single live child process and the crash-restart
+// state machine around it.
+type supervisorProc struct {
+	venvPython string
+	entry      string
+	port       int
+	aud        *audit.Logger
+
+	mu       sync.Mutex
+	cmd      *exec.Cmd
+	open     bool
+	failures []time.Time
+}
+
+func (sv *supervisorProc) currentPID() int {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	if sv.cmd == nil || sv.cmd.Process == nil {
+		return 0
+	}
+	return sv.cmd.Process.Pid
+}
+
+func (sv *supervisorProc) circuitOpen() bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	return sv.open
+}
+
+func (sv *supervisorProc) killCurrent() {
+	sv.mu.Lock()
+	cmd := sv.cmd
+	sv.mu.Unlock()
+	if cmd == nil || cmd.Process == nil {
+		return
+	}
+	killProcessTree(cmd)
+}
+
+// reviveLoop starts the child, waits for it to exit, and restarts it with
+// exponential backoff, tripping a circuit breaker if it keeps crashing
+// within restartWindow so we stop hammering a dead interpreter.
+func (sv *supervisorProc) reviveLoop(ctx context.Context) {
+	backoff := initialBackoff
+	for {
 		select {
-		case <-exited:
-		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return
+		default:
 		}
-		_ = logFile.Close()
-	}
 
-	ready := waitHealthy(ctx, pythonURL, 60*time.Second)
-	if !ready {
+		logR, logW := io.Pipe()
+		go sv.tailStderr(logR)
+
+		cmd := exec.CommandContext(ctx, sv.venvPython, sv.entry)
+		cmd.Env = append(os.Environ(),
+			"PYTHONUNBUFFERED=1",
+			"PYTHON_AGENT_HOST=127.0.0.1",
+			fmt.Sprintf("PYTHON_AGENT_PORT=%d", sv.port),
+		)
+		cmd.Stdout = logW
+		cmd.Stderr = logW
+		setProcessGroup(cmd)
+
+		if err := cmd.Start(); err != nil {
+			_ = logW.Close()
+			sv.aud.Write(audit.Event{Kind: "python_start_failed", Note: err.Error()})
+			if sv.recordFailure() {
+				return
+			}
+			if !sleepOrDone(ctx, backoff) {
+				return
+			}
+			backoff = nextBackoff(backoff)
+			continue
+		}
+		assignToJob(cmd.Process.Pid)
+
+		sv.mu.Lock()
+		sv.cmd = cmd
+		sv.mu.Unlock()
+		backoff = initialBackoff // successful start resets backoff
+
+		waitErr := cmd.Wait()
+		_ = logW.Close()
+
 		select {
-		case err := <-exited:
-			stop()
-			return Result{}, fmt.Errorf("python agent exited during startup: %v", err)
+		case <-ctx.Done():
+			return
 		default:
 		}
-		stop()
-		return Result{}, fmt.Errorf("python agent service failed to start")
+
+		sv.aud.Write(audit.Event{Kind: "python_exit", Note: fmt.Sprintf("exit: %v", waitErr)})
+		if sv.recordFailure() {
+			sv.aud.Write(audit.Event{Kind: "python_circuit_open", Note: fmt.Sprintf("%d failures within %s, giving up", maxFailuresInWindow, restartWindow)})
+			return
+		}
+		if !sleepOrDone(ctx, backoff) {
+			return
+		}
+		backoff = nextBackoff(backoff)
 	}
+}
 
-	return Result{PythonURL: pythonURL, Stop: stop}, nil
+// recordFailure tracks crash timestamps and reports whether the circuit
+// breaker has now tripped.
+func (sv *supervisorProc) recordFailure() bool {
+	sv.mu.Lock()
+	defer sv.mu.Unlock()
+	now := time.Now()
+	cutoff := now.Add(-restartWindow)
+	kept := sv.failures[:0]
+	for _, f := range sv.failures {
+		if f.After(cutoff) {
+			kept = append(kept, f)
+		}
+	}
+	kept = append(kept, now)
+	sv.failures = kept
+	if len(sv.failures) >= maxFailuresInWindow {
+		sv.open = true
+		return true
+	}
+	return false
+}
+
+func (sv *supervisorProc) tailStderr(r io.Reader) {
+	buf := make([]byte, 4096)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			for _, line := range strings.Split(strings.TrimRight(string(buf[:n]), "\n"), "\n") {
+				if strings.TrimSpace(line) == "" {
+					continue
+				}
+				sv.aud.Write(audit.Event{Kind: "python_stderr", Note: line})
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func nextBackoff(cur time.Duration) time.Duration {
+	next := cur * 2
+	if next > maxBackoff {
+		return maxBackoff
+	}
+	return next
+}
+
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
 }
 
 func freeLocalPort() (int, error) {
@@ -132,6 +339,114 @@ func waitHealthy(ctx context.Context, baseURL string, timeout time.Duration) boo
 	return false
 }
 
+// findPythonInterpreter resolves the system interpreter to bootstrap the
+// venv with (PYTHON_BIN env wins, then python3/python, then `py -3` on
+// Windows). baseArgs carries the extra args needed to select Python 3 when
+// invoking the Windows launcher.
+func findPythonInterpreter() (bin string, baseArgs []string, err error) {
+	if v := strings.TrimSpace(os.Getenv("PYTHON_BIN")); v != "" {
+		if p, lerr := exec.LookPath(v); lerr == nil {
+			return p, nil, nil
+		}
+		if fileExists(v) {
+			return v, nil, nil
+		}
+	}
+	for _, c := range []string{"python3", "python"} {
+		if p, lerr := exec.LookPath(c); lerr == nil {
+			return p, nil, nil
+		}
+	}
+	if runtime.GOOS == "windows" {
+		if p, lerr := exec.LookPath("py"); lerr == nil {
+			return p, []string{"-3"}, nil
+		}
+	}
+	return "", nil, errors.New("no python interpreter found (set PYTHON_BIN)")
+}
+
+func ensureVenv(ctx context.Context, pythonBin string, baseArgs []string, venvDir string) error {
+	if fileExists(venvPython(venvDir)) {
+		return nil
+	}
+	args := append(append([]string{}, baseArgs...), "-m", "venv", venvDir)
+	cmd := exec.CommandContext(ctx, pythonBin, args...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+func venvPython(venvDir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(venvDir, "Scripts", "python.exe")
+	}
+	return filepath.Join(venvDir, "bin", "python")
+}
+
+// ensureDeps runs `pip install -r requirements.txt` the first time it's
+// needed, and again whenever the lockfile's contents change; a stamp file
+// next to the venv records the hash we last installed so unchanged
+// requirements don't pay the reinstall cost on every launch.
+func ensureDeps(ctx context.Context, venvDir, reqPath string) error {
+	if !fileExists(reqPath) {
+		return nil
+	}
+	hash, err := hashFile(reqPath)
+	if err != nil {
+		return err
+	}
+	stampPath := filepath.Join(venvDir, ".requirements.sha256")
+	if existing, err := os.ReadFile(stampPath); err == nil && strings.TrimSpace(string(existing)) == hash {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, venvPython(venvDir), "-m", "pip", "install", "-r", reqPath)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return os.WriteFile(stampPath, []byte(hash), 0o644)
+}
+
+func hashFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// appDataDir returns the per-install data directory for the managed venv
+// and logs: $XDG_DATA_HOME/yogz on Linux, %LOCALAPPDATA%\Yogz on Windows,
+// ~/Library/Application Support/Yogz on macOS.
+func appDataDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		base := os.Getenv("LOCALAPPDATA")
+		if strings.TrimSpace(base) == "" {
+			return "", errors.New("%LOCALAPPDATA% not set")
+		}
+		return filepath.Join(base, "Yogz"), nil
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Application Support", "Yogz"), nil
+	default:
+		if base := strings.TrimSpace(os.Getenv("XDG_DATA_HOME")); base != "" {
+			return filepath.Join(base, "yogz"), nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "share", "yogz"), nil
+	}
+}
+
 func resolvePythonAgentEntry(cfgVal string) (string, error) {
 	cfgVal = strings.TrimSpace(cfgVal)
 	candidates := []string{}
@@ -172,16 +487,3 @@ func fileExists(p string) bool {
 	}
 	return !st.IsDir()
 }
-
-func openLogFile(name string) (*os.File, error) {
-	dir := os.Getenv("APPDATA")
-	if strings.TrimSpace(dir) == "" {
-		return nil, fmt.Errorf("APPDATA not set")
-	}
-	logDir := filepath.Join(dir, "Yogz", "logs")
-	if err := os.MkdirAll(logDir, 0o755); err != nil {
-		return nil, err
-	}
-	p := filepath.Join(logDir, name)
-	return os.OpenFile(p, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
-}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 
+	"yogz/go_agent/internal/jsonschema"
 	"yogz/go_agent/internal/security"
 )
 
@@ -20,6 +21,10 @@ func (t *HelpTool) Name() string { return "help" }
 
 func (t *HelpTool) Help() string { return "/help" }
 
+func (t *HelpTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("List available commands", nil)
+}
+
 func (t *HelpTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
 	_ = ctx
 	_ = rt
@@ -48,6 +53,10 @@ func (t *StatusTool) Name() string { return "status" }
 
 func (t *StatusTool) Help() string { return "/status" }
 
+func (t *StatusTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Report agent status (model, limits, fs settings)", nil)
+}
+
 func (t *StatusTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
 	_ = ctx
 	_ = args
@@ -68,6 +77,12 @@ func (t *ModelTool) Name() string { return "model" }
 
 func (t *ModelTool) Help() string { return "/model <name>" }
 
+func (t *ModelTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Get or set the active model", map[string]jsonschema.Schema{
+		"name": jsonschema.String("model name to switch to; omit to read the current model"),
+	})
+}
+
 func (t *ModelTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
 	_ = ctx
 	if rt == nil {
@@ -86,25 +101,21 @@ func (t *ModelTool) Run(ctx context.Context, rt *Runtime, args []string) (string
 	if m == "" {
 		return "usage: /model <name>", nil
 	}
-	// Basic validation against known models
-	known := []string{
-		"gpt-oss:120b-cloud",
-		"deepseek-v3.1:671b-cloud",
-		"qwen3-coder:480b-cloud",
-		"llama3.1:8b",
-		"llama3.1:70b",
-		"mistral:7b",
-		"codellama:7b",
-	}
-	valid := false
-	for _, k := range known {
-		if m == k {
-			valid = true
-			break
+	// Validate against the models advertised by the configured providers
+	// (see config.ProviderSpec) rather than a hardcoded list, so switching
+	// providers or adding model aliases doesn't need a code change here.
+	if rt.ListModels != nil {
+		known := rt.ListModels()
+		valid := false
+		for _, k := range known {
+			if m == k {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Sprintf("unknown model: %s (known: %v)", m, known), nil
 		}
-	}
-	if !valid {
-		return fmt.Sprintf("unknown model: %s (known: %v)", m, known), nil
 	}
 	rt.SetModel(m)
 	return fmt.Sprintf("model: %s", m), nil
@@ -116,8 +127,13 @@ func (t *LSTool) Name() string { return "ls" }
 
 func (t *LSTool) Help() string { return "/ls [path]" }
 
+func (t *LSTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("List the contents of a directory", map[string]jsonschema.Schema{
+		"path": jsonschema.String("directory to list, relative to an allowed root (defaults to '.')"),
+	})
+}
+
 func (t *LSTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
-	_ = ctx
 	if rt == nil {
 		return "filesystem unavailable", nil
 	}
@@ -135,29 +151,60 @@ func (t *LSTool) Run(ctx context.Context, rt *Runtime, args []string) (string, e
 	if !ok {
 		return "path not allowed", nil
 	}
-	ents, err := os.ReadDir(abs)
+	opts, _ := lsOptionsFromContext(ctx)
+	depth := opts.Depth
+	if depth < 1 {
+		depth = 1
+	}
+	items, err := listDir(abs, depth, opts.Hidden)
+	if err != nil {
+		return "ls failed", nil
+	}
+	out := map[string]any{
+		"path":  filepath.Clean(abs),
+		"items": items,
+	}
+	b, err := json.MarshalIndent(out, "", "  ")
 	if err != nil {
 		return "ls failed", nil
 	}
-	type item struct {
-		Name string `json:"name"`
-		Type string `json:"type"`
-		Size int64  `json:"size_bytes"`
+	return string(b), nil
+}
+
+// listDir lists abs up to depth levels deep (1 = just abs's direct
+// children, matching ls's traditional default), skipping dotfiles unless
+// hidden is set. Entries from deeper levels carry a path relative to abs so
+// nested results stay distinguishable from direct children.
+func listDir(abs string, depth int, hidden bool) ([]lsItem, error) {
+	ents, err := os.ReadDir(abs)
+	if err != nil {
+		return nil, err
 	}
-	items := make([]item, 0, len(ents))
+	var items []lsItem
 	for _, e := range ents {
-		it := item{Name: e.Name()}
+		if !hidden && strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		it := lsItem{Name: e.Name()}
 		if e.IsDir() {
 			it.Type = "dir"
-			it.Size = 0
 		} else {
 			it.Type = "file"
-			info, err := e.Info()
-			if err == nil {
+			if info, err := e.Info(); err == nil {
 				it.Size = info.Size()
 			}
 		}
 		items = append(items, it)
+		if e.IsDir() && depth > 1 {
+			sub, err := listDir(filepath.Join(abs, e.Name()), depth-1, hidden)
+			if err != nil {
+				continue
+			}
+			for _, s := range sub {
+				s.Name = filepath.Join(e.Name(), s.Name)
+				items = append(items, s)
+			}
+		}
 	}
 	sort.Slice(items, func(i, j int) bool {
 		if items[i].Type != items[j].Type {
@@ -165,13 +212,11 @@ func (t *LSTool) Run(ctx context.Context, rt *Runtime, args []string) (string, e
 		}
 		return strings.ToLower(items[i].Name) < strings.ToLower(items[j].Name)
 	})
-	out := map[string]any{
-		"path":  filepath.Clean(abs),
-		"items": items,
-	}
-	b, err := json.MarshalIndent(out, "", "  ")
-	if err != nil {
-		return "ls failed", nil
-	}
-	return string(b), nil
+	return items, nil
+}
+
+type lsItem struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+	Size int64  `json:"size_bytes"`
 }
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"context"
+	"strings"
+
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/security"
+)
+
+// catMaxBytes caps how much of a file CatTool will read, matching the cap
+// the /v1/fs/read HTTP handler already enforces.
+const catMaxBytes = 256 * 1024
+
+type CatTool struct{}
+
+func (t *CatTool) Name() string { return "cat" }
+
+func (t *CatTool) Help() string { return "/cat <path> - print a file's contents" }
+
+func (t *CatTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Print the contents of a file", map[string]jsonschema.Schema{
+		"path": jsonschema.String("file to read, relative to an allowed root"),
+	}, "path")
+}
+
+func (t *CatTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
+	_ = ctx
+	if rt == nil {
+		return "filesystem unavailable", nil
+	}
+	if !rt.EnableFS {
+		return "filesystem disabled (set AGENT_ENABLE_FS=true)", nil
+	}
+	if len(args) == 0 {
+		return "usage: /cat <path>", nil
+	}
+	path := strings.TrimSpace(strings.Join(args, " "))
+	if path == "" {
+		return "usage: /cat <path>", nil
+	}
+	abs, err := (security.SafeFS{AllowDirs: rt.AllowDirs}).Resolve(path)
+	if err != nil {
+		return err.Error(), nil
+	}
+	b, err := security.ReadFileLimited(abs, catMaxBytes)
+	if err != nil {
+		return "cat failed: " + err.Error(), nil
+	}
+	return string(b), nil
+}
@@ -0,0 +1,117 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/security"
+	"yogz/go_agent/internal/tokens"
+)
+
+// grepMaxMatches caps how many hits GrepTool reports, and grepMaxChars caps
+// the serialized result, so a broad pattern over a big tree can't blow up
+// the response.
+const (
+	grepMaxMatches = 200
+	grepMaxChars   = 20000
+)
+
+type GrepTool struct{}
+
+func (t *GrepTool) Name() string { return "grep" }
+
+func (t *GrepTool) Help() string { return "/grep <pattern> <path> - search files for a regex match" }
+
+func (t *GrepTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Search files under path for lines matching a regular expression", map[string]jsonschema.Schema{
+		"pattern": jsonschema.String("regular expression to search for"),
+		"path":    jsonschema.String("file or directory to search, relative to an allowed root"),
+	}, "pattern", "path")
+}
+
+func (t *GrepTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
+	_ = ctx
+	if rt == nil {
+		return "filesystem unavailable", nil
+	}
+	if !rt.EnableFS {
+		return "filesystem disabled (set AGENT_ENABLE_FS=true)", nil
+	}
+	if len(args) < 2 {
+		return "usage: /grep <pattern> <path>", nil
+	}
+	pattern := args[0]
+	path := strings.TrimSpace(strings.Join(args[1:], " "))
+	if path == "" {
+		return "usage: /grep <pattern> <path>", nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return "invalid pattern: " + err.Error(), nil
+	}
+
+	fs := security.SafeFS{AllowDirs: rt.AllowDirs}
+	root, err := fs.Resolve(path)
+	if err != nil {
+		return err.Error(), nil
+	}
+
+	var matches []string
+	done := fmt.Errorf("grep: match limit reached")
+	walkErr := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil // skip entries we can't stat (e.g. permission denied)
+		}
+		if len(matches) >= grepMaxMatches {
+			return done
+		}
+		if d.Type()&os.ModeSymlink != 0 {
+			// Never follow a symlink out of the tree we already resolved
+			// into the sandbox.
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		grepFile(p, re, &matches)
+		return nil
+	})
+	if walkErr != nil && walkErr != done {
+		return "grep failed: " + walkErr.Error(), nil
+	}
+	if len(matches) == 0 {
+		return "no matches", nil
+	}
+	return tokens.ClampChars(strings.Join(matches, "\n"), grepMaxChars), nil
+}
+
+func grepFile(path string, re *regexp.Regexp, matches *[]string) {
+	f, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		if len(*matches) >= grepMaxMatches {
+			return
+		}
+		line := scanner.Text()
+		if re.MatchString(line) {
+			*matches = append(*matches, fmt.Sprintf("%s:%d:%s", path, lineNo, line))
+		}
+	}
+}
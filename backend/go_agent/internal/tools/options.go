@@ -0,0 +1,65 @@
+package tools
+
+import (
+	"context"
+	"time"
+)
+
+// RunOptions carries the CLI-only --timeout/--cwd flags the cobra "run"
+// subcommand (see internal/clicmd) parses for the run tool. They travel via
+// context rather than fields on RunTool itself, since RunTool is a shared
+// singleton and a per-call struct field would race under the planner's
+// concurrent tool dispatch.
+type RunOptions struct {
+	Timeout time.Duration
+	Cwd     string
+	// DryRun, if set, makes RunTool print the command as normalizeCommand
+	// (see shim.go) would actually execute it, without running it.
+	DryRun bool
+}
+
+type runOptionsKey struct{}
+
+func WithRunOptions(ctx context.Context, opts RunOptions) context.Context {
+	return context.WithValue(ctx, runOptionsKey{}, opts)
+}
+
+func runOptionsFromContext(ctx context.Context) (RunOptions, bool) {
+	opts, ok := ctx.Value(runOptionsKey{}).(RunOptions)
+	return opts, ok
+}
+
+// LSOptions carries the CLI-only --depth/--hidden flags the cobra "ls"
+// subcommand parses for the ls tool.
+type LSOptions struct {
+	Depth  int
+	Hidden bool
+}
+
+type lsOptionsKey struct{}
+
+func WithLSOptions(ctx context.Context, opts LSOptions) context.Context {
+	return context.WithValue(ctx, lsOptionsKey{}, opts)
+}
+
+func lsOptionsFromContext(ctx context.Context) (LSOptions, bool) {
+	opts, ok := ctx.Value(lsOptionsKey{}).(LSOptions)
+	return opts, ok
+}
+
+// PyOptions carries the CLI-only --venv flag the cobra "py" subcommand
+// parses for the py tool.
+type PyOptions struct {
+	Venv bool
+}
+
+type pyOptionsKey struct{}
+
+func WithPyOptions(ctx context.Context, opts PyOptions) context.Context {
+	return context.WithValue(ctx, pyOptionsKey{}, opts)
+}
+
+func pyOptionsFromContext(ctx context.Context) (PyOptions, bool) {
+	opts, ok := ctx.Value(pyOptionsKey{}).(PyOptions)
+	return opts, ok
+}
@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/security"
+)
+
+type PatchTool struct{}
+
+func (t *PatchTool) Name() string { return "patch" }
+
+func (t *PatchTool) Help() string {
+	return "/patch <path> <unified-diff> - apply a unified diff to a file"
+}
+
+func (t *PatchTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Apply a unified diff to an existing file", map[string]jsonschema.Schema{
+		"path": jsonschema.String("file to patch, relative to an allowed root"),
+		"diff": jsonschema.String("unified diff (as produced by `diff -u` or `git diff`) to apply"),
+	}, "path", "diff")
+}
+
+func (t *PatchTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
+	_ = ctx
+	if rt == nil {
+		return "filesystem unavailable", nil
+	}
+	if !rt.EnableFS {
+		return "filesystem disabled (set AGENT_ENABLE_FS=true)", nil
+	}
+	if len(args) < 2 {
+		return "usage: /patch <path> <unified-diff>", nil
+	}
+	path := strings.TrimSpace(args[0])
+	diff := strings.Join(args[1:], " ")
+	if path == "" || strings.TrimSpace(diff) == "" {
+		return "usage: /patch <path> <unified-diff>", nil
+	}
+
+	fs := security.SafeFS{AllowDirs: rt.AllowDirs}
+	abs, err := fs.Resolve(path)
+	if err != nil {
+		return err.Error(), nil
+	}
+	orig, err := security.ReadFileLimited(abs, catMaxBytes)
+	if err != nil {
+		return "patch failed: " + err.Error(), nil
+	}
+
+	patched, err := applyUnifiedDiff(string(orig), diff)
+	if err != nil {
+		return "patch failed: " + err.Error(), nil
+	}
+
+	maxBytes := rt.MaxWriteBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxWriteBytes
+	}
+	if int64(len(patched)) > maxBytes {
+		return fmt.Sprintf("patched content too large: %d bytes exceeds the %d byte limit", len(patched), maxBytes), nil
+	}
+
+	// Re-resolve for the write half of the operation: Resolve above only
+	// guarantees abs existed and was allowed at read time, and
+	// ResolveForWrite additionally refuses to write through a symlink.
+	writeAbs, err := fs.ResolveForWrite(path)
+	if err != nil {
+		return err.Error(), nil
+	}
+	if err := atomicWrite(writeAbs, []byte(patched)); err != nil {
+		return "patch failed: " + err.Error(), nil
+	}
+	return fmt.Sprintf("patched %s (%d bytes)", writeAbs, len(patched)), nil
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+type hunk struct {
+	oldStart int
+	lines    []string // each prefixed with ' ', '+' or '-'
+}
+
+// applyUnifiedDiff applies a unified diff (as produced by `diff -u` or
+// `git diff`, file headers and all) to original and returns the patched
+// text. It's a hand-rolled, single-file applier rather than a full patch(1)
+// clone: it only understands the hunk format and ignores "---"/"+++"/
+// "diff "/"index " header lines, since PatchTool always targets the one
+// file SafeFS already resolved.
+func applyUnifiedDiff(original, diff string) (string, error) {
+	hunks, err := parseHunks(diff)
+	if err != nil {
+		return "", err
+	}
+	if len(hunks) == 0 {
+		return "", fmt.Errorf("no hunks found in diff")
+	}
+
+	origLines := strings.Split(original, "\n")
+	var out []string
+	pos := 0 // 0-based index into origLines
+	for _, h := range hunks {
+		start := h.oldStart - 1
+		if start < pos || start > len(origLines) {
+			return "", fmt.Errorf("hunk at line %d is out of order or out of range", h.oldStart)
+		}
+		out = append(out, origLines[pos:start]...)
+		pos = start
+		for _, l := range h.lines {
+			if l == "" {
+				continue
+			}
+			switch l[0] {
+			case ' ':
+				if pos >= len(origLines) || origLines[pos] != l[1:] {
+					return "", fmt.Errorf("context mismatch at line %d", pos+1)
+				}
+				out = append(out, origLines[pos])
+				pos++
+			case '-':
+				if pos >= len(origLines) || origLines[pos] != l[1:] {
+					return "", fmt.Errorf("delete mismatch at line %d", pos+1)
+				}
+				pos++
+			case '+':
+				out = append(out, l[1:])
+			default:
+				return "", fmt.Errorf("invalid diff line: %q", l)
+			}
+		}
+	}
+	out = append(out, origLines[pos:]...)
+	return strings.Join(out, "\n"), nil
+}
+
+func parseHunks(diff string) ([]hunk, error) {
+	var hunks []hunk
+	var cur *hunk
+	for _, line := range strings.Split(diff, "\n") {
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			if cur != nil {
+				hunks = append(hunks, *cur)
+			}
+			oldStart, err := strconv.Atoi(m[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid hunk header: %q", line)
+			}
+			cur = &hunk{oldStart: oldStart}
+			continue
+		}
+		if cur == nil {
+			continue // skip file headers (---, +++) and anything before the first hunk
+		}
+		if strings.HasPrefix(line, "\\ No newline") {
+			continue
+		}
+		cur.lines = append(cur.lines, line)
+	}
+	if cur != nil {
+		hunks = append(hunks, *cur)
+	}
+	return hunks, nil
+}
@@ -1,13 +1,11 @@
 package tools
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
-	"net/http"
 	"strings"
-	"time"
+
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/pyclient"
 )
 
 type PythonTool struct{}
@@ -16,6 +14,12 @@ func (t *PythonTool) Name() string { return "py" }
 
 func (t *PythonTool) Help() string { return "/py <question>" }
 
+func (t *PythonTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Forward a natural-language question to the Python agent service", map[string]jsonschema.Schema{
+		"question": jsonschema.String("the question to ask"),
+	}, "question")
+}
+
 func (t *PythonTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
 	if rt == nil || strings.TrimSpace(rt.PythonToolsURL) == "" {
 		return "python tool server not configured (set PYTOOLS_URL)", nil
@@ -26,30 +30,11 @@ func (t *PythonTool) Run(ctx context.Context, rt *Runtime, args []string) (strin
 
 	// Treat all args as a single natural-language query
 	question := strings.Join(args, " ")
-	payload := map[string]any{"question": question, "user_id": "cli", "model": rt.GetModel()}
-	body, _ := json.Marshal(payload)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(rt.PythonToolsURL, "/")+"/ask", bytes.NewReader(body))
-	if err != nil {
-		return "", err
-	}
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
+	opts, _ := pyOptionsFromContext(ctx)
+	client := pyclient.New(rt.PythonToolsURL)
+	resp, err := client.Ask(ctx, pyclient.AskRequest{Question: question, UserID: "cli", Model: rt.GetModel(), UseVenv: opts.Venv})
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Sprintf("python service error: %d", resp.StatusCode), nil
-	}
-	var out struct {
-		Answer string `json:"answer"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("invalid response from python service")
-	}
-	return out.Answer, nil
+	return resp.Answer, nil
 }
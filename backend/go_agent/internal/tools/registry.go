@@ -5,10 +5,32 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
+
+	"yogz/go_agent/internal/jsonschema"
 )
 
+// ToolSpec is the machine-readable description of a registered tool: its
+// name, a human description and a JSON Schema for its arguments. It is what
+// gets handed to the model (and to /v1/tools/schema) so callers can form
+// well-typed tool calls without reading source.
+type ToolSpec struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Parameters  jsonschema.Schema `json:"parameters"`
+}
+
+// AuditFunc, if set on a Registry, is called after every Run with the tool
+// name, its args, the duration it took, a copy of its output (callers
+// should truncate before logging it), and any error -- giving every tool
+// invocation through that Registry a uniform audit trail without each call
+// site having to remember to record one. The API server's Registry sets it
+// (covering /v1/tools/run and planner dispatch); the CLI's leaves it unset.
+type AuditFunc func(ctx context.Context, name string, args []string, out string, dur time.Duration, err error)
+
 type Registry struct {
 	tools map[string]Tool
+	Audit AuditFunc
 }
 
 func NewRegistry(ts ...Tool) *Registry {
@@ -45,10 +67,38 @@ func (r *Registry) Names() []string {
 	return out
 }
 
+// Manifest returns a ToolSpec for every registered tool, sorted by name, for
+// use as a system-prompt tool manifest or an API response body.
+func (r *Registry) Manifest() []ToolSpec {
+	names := r.Names()
+	specs := make([]ToolSpec, 0, len(names))
+	for _, name := range names {
+		t, ok := r.Get(name)
+		if !ok {
+			continue
+		}
+		specs = append(specs, ToolSpec{
+			Name:        t.Name(),
+			Description: t.Help(),
+			Parameters:  t.Schema(),
+		})
+	}
+	return specs
+}
+
 func (r *Registry) Run(ctx context.Context, rt *Runtime, name string, args []string) (string, error) {
 	t, ok := r.Get(name)
 	if !ok {
-		return "", fmt.Errorf("unknown command: %s", name)
+		err := fmt.Errorf("unknown command: %s", name)
+		if r.Audit != nil {
+			r.Audit(ctx, name, args, "", 0, err)
+		}
+		return "", err
+	}
+	start := time.Now()
+	out, err := t.Run(ctx, rt, args)
+	if r.Audit != nil {
+		r.Audit(ctx, name, args, out, time.Since(start), err)
 	}
-	return t.Run(ctx, rt, args)
+	return out, err
 }
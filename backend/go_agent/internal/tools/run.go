@@ -1,24 +1,144 @@
 package tools
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"time"
+
+	"yogz/go_agent/internal/config"
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/security"
 )
 
+// RunPolicy bounds what RunTool will actually execute: an LLM can drive this
+// tool, so it gets the same "don't trust the input" treatment as any other
+// untrusted caller. All fields are optional; the zero value applies no
+// allow/deny list, no timeout beyond ctx and no output cap, and forwards
+// only PATH from the environment (see AllowEnv).
+type RunPolicy struct {
+	// AllowExec, if non-empty, is the only set of executables (matched by
+	// basename against the command's first token) RunTool will run.
+	// DenyExec always wins over AllowExec.
+	AllowExec []string
+	DenyExec  []string
+
+	// Timeout bounds wall-clock execution independent of ctx's own
+	// deadline; RunOptions.Timeout (the CLI's --timeout flag) overrides it
+	// per-call when set.
+	Timeout time.Duration
+	// MaxOutputBytes caps combined stdout/stderr; output beyond the cap is
+	// dropped and a truncation notice appended. 0 means no cap.
+	MaxOutputBytes int
+	// AllowEnv whitelists environment variable names forwarded to the
+	// child. A nil/empty list forwards nothing but the bare minimum PATH.
+	AllowEnv []string
+	// ConfineCwd, if set, requires the resolved working directory to fall
+	// within AllowDirs.
+	ConfineCwd bool
+
+	// LimitCPUSeconds/LimitAddressSpaceMB/LimitNoFile, if > 0, are applied
+	// via `ulimit` ahead of the command on non-Windows targets.
+	LimitCPUSeconds     int
+	LimitAddressSpaceMB int
+	LimitNoFile         int
+}
+
+// NewRunPolicy builds a RunPolicy from the AGENT_RUN_* config fields (see
+// config.Config's doc comments), the same way audit.NewFromConfig builds a
+// Logger from its AGENT_AUDIT_* fields.
+func NewRunPolicy(cfg config.Config) RunPolicy {
+	return RunPolicy{
+		AllowExec:           cfg.RunAllowExec,
+		DenyExec:            cfg.RunDenyExec,
+		Timeout:             cfg.RunTimeout,
+		MaxOutputBytes:      cfg.RunMaxOutputBytes,
+		AllowEnv:            cfg.RunAllowEnv,
+		ConfineCwd:          cfg.RunConfineCwd,
+		LimitCPUSeconds:     cfg.RunLimitCPUSeconds,
+		LimitAddressSpaceMB: cfg.RunLimitAddressSpaceMB,
+		LimitNoFile:         cfg.RunLimitNoFile,
+	}
+}
+
 type RunTool struct{}
 
 func (t *RunTool) Name() string { return "run" }
 
-func (t *RunTool) Help() string { return "/run <command> [args...] – execute shell/PowerShell command" }
+func (t *RunTool) Help() string {
+	return "/run <command> [args...] – execute shell/PowerShell command"
+}
+
+func (t *RunTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Execute a shell command and return its combined output", map[string]jsonschema.Schema{
+		"command": jsonschema.String("the command line to execute, shell-quoted"),
+	}, "command")
+}
 
 func (t *RunTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
 	if len(args) == 0 {
 		return "usage: /run <command> [args...]", nil
 	}
-	cmdLine := strings.Join(args, " ")
+	var policy RunPolicy
+	if rt != nil {
+		policy = rt.RunPolicy
+	}
+	// args may be a single element holding the whole command line --
+	// RunTool's JSON schema declares one "command" string property, so the
+	// planner's flattenArgs never shell-splits it -- so the executable
+	// must come from the joined line's first field, the same way the
+	// RunAllowed helper already derives it, not from args[0] itself.
+	//
+	// When there's more than one element, args instead came pre-tokenized
+	// from clicmd.Dispatch's splitShellWords (e.g. `/run echo "hello
+	// world"`), which already consumed the quoting that kept "hello world"
+	// one word. Rejoining those tokens with plain spaces would lose that
+	// again, so each token gets re-quoted first -- a no-op for the
+	// single-element planner case, where args[0] is the raw line, not a
+	// token to quote.
+	cmdStr := joinShellArgs(args)
+	exeName := resolveExecName(firstField(cmdStr))
+	if !runAllowed(policy, exeName) {
+		return fmt.Sprintf("command not allowed: %s (blocked by run policy)", exeName), nil
+	}
+	// An allow/deny list only means something if it bounds what actually
+	// runs: since the command still reaches a real shell below (sh -c /
+	// powershell -Command), shell metacharacters let an allowed executable
+	// chain into a disallowed one (e.g. "ls; rm -rf /tmp" passes runAllowed
+	// on "ls" even under DenyExec=["rm"] alone, then runs the rm anyway).
+	// Once either list is configured, refuse those outright rather than
+	// only checking the first token.
+	if len(policy.AllowExec) > 0 || len(policy.DenyExec) > 0 {
+		if bad, ok := firstShellMetachar(cmdStr); ok {
+			return fmt.Sprintf("command not allowed: contains shell metacharacter %q, which an allow/deny list can't see past", bad), nil
+		}
+	}
+
+	opts, _ := runOptionsFromContext(ctx)
+	timeout := policy.Timeout
+	if opts.Timeout > 0 {
+		timeout = opts.Timeout
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	cmdLine, applied := normalizeCommand(cmdStr)
+	if opts.DryRun {
+		if len(applied) == 0 {
+			return cmdLine, nil
+		}
+		return fmt.Sprintf("%s\n(shims applied: %s)", cmdLine, strings.Join(applied, ", ")), nil
+	}
+	cmdLine = applyRlimits(cmdLine, policy)
+
 	var cmd *exec.Cmd
 	if runtime.GOOS == "windows" {
 		// Prefer PowerShell for richer experience; fallback to cmd if unavailable
@@ -35,11 +155,28 @@ func (t *RunTool) Run(ctx context.Context, rt *Runtime, args []string) (string,
 		}
 		cmd = exec.CommandContext(ctx, shell, "-c", cmdLine)
 	}
-	// Inherit the current process environment and working directory
-	cmd.Env = os.Environ()
+
+	cmd.Env = scrubEnv(policy.AllowEnv)
 	cmd.Dir = "."
-	out, err := cmd.CombinedOutput()
-	res := string(out)
+	if opts.Cwd != "" {
+		cmd.Dir = opts.Cwd
+	}
+	if policy.ConfineCwd && rt != nil {
+		abs, ok := security.IsPathAllowed(cmd.Dir, rt.AllowDirs)
+		if !ok {
+			return fmt.Sprintf("working directory not allowed: %s", cmd.Dir), nil
+		}
+		cmd.Dir = abs
+	}
+
+	var buf capBuffer
+	if policy.MaxOutputBytes > 0 {
+		buf.limit = policy.MaxOutputBytes
+	}
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+	err := cmd.Run()
+	res := buf.String()
 	if err != nil {
 		// Include error text but still return whatever we got
 		if res != "" {
@@ -49,3 +186,196 @@ func (t *RunTool) Run(ctx context.Context, rt *Runtime, args []string) (string,
 	}
 	return res, nil
 }
+
+// shellMetachars are the characters that let a command string reach past
+// its first token under "sh -c"/"powershell -Command": chaining (; & |),
+// substitution/grouping ($ ` ( )), and redirection (< >).
+const shellMetachars = ";&|$`()<>\n"
+
+// firstShellMetachar reports the first shell metacharacter in cmdLine, if
+// any, so RunTool.Run can refuse it when an allow or deny list is configured --
+// otherwise the allow-list only ever sees the command's first token, not
+// whatever it's chained with.
+func firstShellMetachar(cmdLine string) (string, bool) {
+	if i := strings.IndexAny(cmdLine, shellMetachars); i >= 0 {
+		return string(cmdLine[i]), true
+	}
+	return "", false
+}
+
+// firstField returns cmdLine's first whitespace-delimited token, or "" for
+// an empty/blank command line. RunTool.Run and RunAllowed both derive the
+// executable to check against the run policy from this, rather than from
+// whatever positional args they were handed -- the planner's flattenArgs
+// hands RunTool a single arg holding the *whole* command line (the "run"
+// tool's schema declares one "command" string property), so args[0] is not
+// reliably the executable on its own.
+func firstField(cmdLine string) string {
+	fields := strings.Fields(cmdLine)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// joinShellArgs rebuilds a single sh -c command line from args. A single
+// element is returned unchanged -- it's already the raw command line (see
+// the comment at RunTool.Run's call site). Multiple elements are assumed to
+// be discrete pre-tokenized arguments (e.g. from clicmd.splitShellWords), so
+// each is re-quoted via quoteShellArg before joining, or a token containing
+// whitespace would fragment into several words on rejoin.
+func joinShellArgs(args []string) string {
+	if len(args) <= 1 {
+		return strings.Join(args, " ")
+	}
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = quoteShellArg(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteShellArg single-quotes s if it contains anything a shell would
+// otherwise re-split or reinterpret -- whitespace, quotes, or other shell
+// metacharacters -- escaping any embedded single quote the way sh itself
+// requires ('\''). Left unchanged when that's unnecessary, so plain tokens
+// stay readable in "/run --dry-run" output.
+func quoteShellArg(s string) string {
+	if s == "" {
+		return "''"
+	}
+	if !strings.ContainsAny(s, " \t\n'\"\\"+shellMetachars) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// resolveExecName returns the basename of the command's first token, the
+// unit allow/deny lists are matched against. It's resolved via LookPath
+// when possible so e.g. "./rm" and a bare "rm" on PATH are judged the same.
+func resolveExecName(first string) string {
+	if first == "" {
+		return ""
+	}
+	if path, err := exec.LookPath(first); err == nil {
+		first = path
+	}
+	return filepath.Base(first)
+}
+
+// RunAllowed reports whether cmdLine's first token is permitted to run
+// under policy. Exported so callers that see a command before it reaches
+// RunTool.Run -- e.g. agent.RunCLI's plan-produced-command confirmation
+// prompt -- can check the same allow/deny list RunTool itself enforces.
+func RunAllowed(policy RunPolicy, cmdLine string) bool {
+	first := firstField(cmdLine)
+	if first == "" {
+		return true
+	}
+	return runAllowed(policy, resolveExecName(first))
+}
+
+// runAllowed reports whether exeName may run under policy: DenyExec always
+// wins, then AllowExec (when non-empty) must contain it.
+func runAllowed(policy RunPolicy, exeName string) bool {
+	for _, d := range policy.DenyExec {
+		if strings.EqualFold(d, exeName) {
+			return false
+		}
+	}
+	if len(policy.AllowExec) == 0 {
+		return true
+	}
+	for _, a := range policy.AllowExec {
+		if strings.EqualFold(a, exeName) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrubEnv builds the child's environment from os.Environ(), keeping only
+// the names in allow (plus PATH, always, so the shell can resolve the
+// command at all). An empty allow list still forwards PATH alone.
+func scrubEnv(allow []string) []string {
+	keep := map[string]bool{"PATH": true}
+	for _, k := range allow {
+		keep[strings.ToUpper(k)] = true
+	}
+	var out []string
+	for _, kv := range os.Environ() {
+		name, _, ok := strings.Cut(kv, "=")
+		if !ok {
+			continue
+		}
+		if keep[strings.ToUpper(name)] {
+			out = append(out, kv)
+		}
+	}
+	return out
+}
+
+// applyRlimits prefixes cmdLine with `ulimit` invocations for whichever
+// limits policy sets, so RunTool doesn't need a cgo dependency or a
+// syscall.SysProcAttr rlimit field Go's exec package doesn't expose --
+// ulimit fits naturally since the command already runs under "sh -c".
+// Windows has no ulimit equivalent, so this is a no-op there.
+func applyRlimits(cmdLine string, policy RunPolicy) string {
+	if runtime.GOOS == "windows" {
+		return cmdLine
+	}
+	var prefix strings.Builder
+	if policy.LimitCPUSeconds > 0 {
+		fmt.Fprintf(&prefix, "ulimit -t %d; ", policy.LimitCPUSeconds)
+	}
+	if policy.LimitAddressSpaceMB > 0 {
+		fmt.Fprintf(&prefix, "ulimit -v %d; ", policy.LimitAddressSpaceMB*1024)
+	}
+	if policy.LimitNoFile > 0 {
+		fmt.Fprintf(&prefix, "ulimit -n %d; ", policy.LimitNoFile)
+	}
+	if prefix.Len() == 0 {
+		return cmdLine
+	}
+	return prefix.String() + cmdLine
+}
+
+// capBuffer is an io.Writer that keeps at most limit bytes (0 = unlimited),
+// dropping everything past that and noting the truncation once rather than
+// growing without bound on a runaway command's output.
+type capBuffer struct {
+	buf        bytes.Buffer
+	limit      int
+	truncated  bool
+	overflowed int
+}
+
+func (c *capBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.limit <= 0 {
+		c.buf.Write(p)
+		return n, nil
+	}
+	room := c.limit - c.buf.Len()
+	if room <= 0 {
+		c.truncated = true
+		c.overflowed += n
+		return n, nil
+	}
+	if len(p) > room {
+		c.buf.Write(p[:room])
+		c.truncated = true
+		c.overflowed += len(p) - room
+		return n, nil
+	}
+	c.buf.Write(p)
+	return n, nil
+}
+
+func (c *capBuffer) String() string {
+	s := c.buf.String()
+	if c.truncated {
+		s = strings.TrimRight(s, "\n") + fmt.Sprintf("\n... output truncated (%d bytes dropped)\n", c.overflowed)
+	}
+	return s
+}
@@ -0,0 +1,174 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunAllowedDenyListWinsOverAllowList(t *testing.T) {
+	policy := RunPolicy{AllowExec: []string{"rm"}, DenyExec: []string{"rm"}}
+	if RunAllowed(policy, "rm -rf /tmp/important") {
+		t.Fatalf("RunAllowed with rm on both allow and deny lists = true, want false")
+	}
+}
+
+func TestRunAllowedFullCommandLineNotJustFirstToken(t *testing.T) {
+	// Regression: the executable must come from the command line's first
+	// whitespace-delimited token, not the whole joined string -- "rm -rf
+	// /tmp/important" must resolve to "rm", not be left unmatched because
+	// the whole string doesn't equal any entry in DenyExec.
+	policy := RunPolicy{DenyExec: []string{"rm"}}
+	if RunAllowed(policy, "rm -rf /tmp/important") {
+		t.Fatalf("RunAllowed(%q) = true, want false (rm is denied)", "rm -rf /tmp/important")
+	}
+}
+
+func TestRunAllowedAllowListPermitsMultiWordCommand(t *testing.T) {
+	policy := RunPolicy{AllowExec: []string{"ls"}}
+	if !RunAllowed(policy, "ls -la") {
+		t.Fatalf("RunAllowed(%q) = false, want true (ls is allowed)", "ls -la")
+	}
+}
+
+func TestRunAllowedAllowListRejectsUnlistedExecutable(t *testing.T) {
+	policy := RunPolicy{AllowExec: []string{"ls"}}
+	if RunAllowed(policy, "cat /etc/passwd") {
+		t.Fatalf("RunAllowed(%q) = true, want false (cat not on allow list)", "cat /etc/passwd")
+	}
+}
+
+func TestRunAllowedEmptyCommandLine(t *testing.T) {
+	policy := RunPolicy{AllowExec: []string{"ls"}}
+	if !RunAllowed(policy, "") {
+		t.Fatalf("RunAllowed(\"\") = false, want true (nothing to deny)")
+	}
+}
+
+func TestRunAllowedZeroValuePolicyAllowsEverything(t *testing.T) {
+	var policy RunPolicy
+	if !RunAllowed(policy, "rm -rf /") {
+		t.Fatalf("RunAllowed under zero-value policy = false, want true (no restrictions configured)")
+	}
+}
+
+func TestRunAllowedCaseInsensitive(t *testing.T) {
+	policy := RunPolicy{DenyExec: []string{"RM"}}
+	if RunAllowed(policy, "rm -rf /tmp/x") {
+		t.Fatalf("RunAllowed should match deny entries case-insensitively")
+	}
+}
+
+func TestFirstFieldExtractsExecutableFromCommandLine(t *testing.T) {
+	cases := map[string]string{
+		"ls -la":              "ls",
+		"  rm  -rf /tmp/x":    "rm",
+		"echo hello":          "echo",
+		"":                    "",
+		"   ":                 "",
+		"single-word-no-args": "single-word-no-args",
+	}
+	for in, want := range cases {
+		if got := firstField(in); got != want {
+			t.Errorf("firstField(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFirstShellMetacharDetectsChaining(t *testing.T) {
+	bad, ok := firstShellMetachar("ls; rm -rf /tmp/x")
+	if !ok || bad != ";" {
+		t.Fatalf("firstShellMetachar(%q) = (%q, %v), want (\";\", true)", "ls; rm -rf /tmp/x", bad, ok)
+	}
+
+	if _, ok := firstShellMetachar("ls -la /tmp"); ok {
+		t.Fatalf("firstShellMetachar(%q) reported a metacharacter, want none", "ls -la /tmp")
+	}
+}
+
+func TestRunRejectsShellMetacharacterWhenAllowListConfigured(t *testing.T) {
+	tool := &RunTool{}
+	rt := &Runtime{RunPolicy: RunPolicy{AllowExec: []string{"ls"}}}
+	out, err := tool.Run(context.Background(), rt, []string{"ls /tmp; rm -rf /tmp/x"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !strings.Contains(out, "not allowed") || !strings.Contains(out, "shell metacharacter") {
+		t.Fatalf("Run output = %q, want a message rejecting the shell metacharacter", out)
+	}
+}
+
+func TestRunRejectsShellMetacharacterWithDenyListOnlyConfigured(t *testing.T) {
+	// Regression: a deny-list-only policy ("ls; rm -rf /tmp" under
+	// DenyExec=["rm"] alone) must not slip through just because no
+	// AllowExec is set -- the first token ("ls") isn't denied, so without
+	// this check the whole string would still reach sh -c and run rm.
+	tool := &RunTool{}
+	rt := &Runtime{RunPolicy: RunPolicy{DenyExec: []string{"rm"}}}
+	out, err := tool.Run(context.Background(), rt, []string{"ls; rm -rf /tmp/x"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !strings.Contains(out, "not allowed") || !strings.Contains(out, "shell metacharacter") {
+		t.Fatalf("Run output = %q, want a message rejecting the shell metacharacter under a deny-list-only policy", out)
+	}
+}
+
+func TestJoinShellArgsPreservesQuotedMultiWordToken(t *testing.T) {
+	// Regression: clicmd.Dispatch hands RunTool.Run the already-tokenized
+	// fields splitShellWords produced, e.g. `/run echo "hello world"` ->
+	// ["echo", "hello world"]. Plain strings.Join would lose the quoting
+	// that kept "hello world" one word, turning it into two.
+	got := joinShellArgs([]string{"echo", "hello world"})
+	want := `echo 'hello world'`
+	if got != want {
+		t.Fatalf("joinShellArgs = %q, want %q", got, want)
+	}
+}
+
+func TestJoinShellArgsSingleElementPassesThroughUnquoted(t *testing.T) {
+	// The planner path hands RunTool.Run the whole command line as one
+	// element (see RunTool.Run's comment) -- it must reach the shell
+	// exactly as written, not get wrapped in quotes.
+	got := joinShellArgs([]string{"ls -la; echo hi"})
+	want := "ls -la; echo hi"
+	if got != want {
+		t.Fatalf("joinShellArgs = %q, want %q", got, want)
+	}
+}
+
+func TestQuoteShellArgEscapesEmbeddedSingleQuote(t *testing.T) {
+	got := quoteShellArg(`it's here`)
+	want := `'it'\''s here'`
+	if got != want {
+		t.Fatalf("quoteShellArg = %q, want %q", got, want)
+	}
+}
+
+func TestRunPreservesQuotedMultiWordArgument(t *testing.T) {
+	// End-to-end version of TestJoinShellArgsPreservesQuotedMultiWordToken:
+	// the tokenized fields a REPL dispatch would pass must make it through
+	// Run to a single "echo" invocation with "hello world" as one argument,
+	// not two.
+	tool := &RunTool{}
+	rt := &Runtime{}
+	out, err := tool.Run(context.Background(), rt, []string{"echo", "hello world"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if strings.TrimSpace(out) != "hello world" {
+		t.Fatalf("Run output = %q, want %q", out, "hello world")
+	}
+}
+
+func TestRunDeniesExecutableNotOnAllowListBeforeExecuting(t *testing.T) {
+	tool := &RunTool{}
+	rt := &Runtime{RunPolicy: RunPolicy{AllowExec: []string{"ls"}}}
+	out, err := tool.Run(context.Background(), rt, []string{"cat /etc/passwd"})
+	if err != nil {
+		t.Fatalf("Run returned error: %v", err)
+	}
+	if !strings.Contains(out, "not allowed") {
+		t.Fatalf("Run output = %q, want a message blocking the disallowed command", out)
+	}
+}
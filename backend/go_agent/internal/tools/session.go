@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/security"
+)
+
+// SessionTool exposes session management on top of the caller's active
+// memory.Session through the Runtime.Session* hooks: "new" starts a fresh
+// one, "list" shows the user's history, "load <id>" switches the active
+// session, "save <path>" exports it (JSON, or markdown for a ".md" path),
+// "replay <id>" prints a transcript, and "fork" branches the active session
+// so an alternative prompt can be explored without losing history.
+type SessionTool struct{}
+
+func (t *SessionTool) Name() string { return "session" }
+
+func (t *SessionTool) Help() string {
+	return "/session new|list|load <id>|save <path>|replay <id>|fork"
+}
+
+func (t *SessionTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Manage conversation sessions (new, list, load, save, replay, fork)", map[string]jsonschema.Schema{
+		"subcommand": jsonschema.String("one of: new, list, load, save, replay, fork"),
+		"arg":        jsonschema.String("the session id (load/replay) or output path (save)"),
+	}, "subcommand")
+}
+
+func (t *SessionTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
+	_ = ctx
+	if rt == nil {
+		return "sessions unavailable", nil
+	}
+	if len(args) == 0 {
+		return "usage: " + t.Help(), nil
+	}
+	sub := strings.ToLower(args[0])
+	rest := args[1:]
+
+	switch sub {
+	case "new":
+		if rt.SessionNew == nil {
+			return "sessions unavailable", nil
+		}
+		info := rt.SessionNew()
+		return fmt.Sprintf("new session: %s", info.ID), nil
+
+	case "list":
+		if rt.SessionList == nil {
+			return "sessions unavailable", nil
+		}
+		sessions := rt.SessionList()
+		if len(sessions) == 0 {
+			return "no sessions yet", nil
+		}
+		var b strings.Builder
+		for _, s := range sessions {
+			fmt.Fprintf(&b, "%s  %s  model=%s  turns=%d\n", s.ID, s.CreatedAt.Format(time.RFC3339), s.Model, s.Turns)
+		}
+		return strings.TrimSpace(b.String()), nil
+
+	case "load":
+		if rt.SessionLoad == nil {
+			return "sessions unavailable", nil
+		}
+		if len(rest) == 0 {
+			return "usage: /session load <id>", nil
+		}
+		if err := rt.SessionLoad(rest[0]); err != nil {
+			return err.Error(), nil
+		}
+		return fmt.Sprintf("active session: %s", rest[0]), nil
+
+	case "save":
+		if rt.SessionSave == nil {
+			return "sessions unavailable", nil
+		}
+		if len(rest) == 0 {
+			return "usage: /session save <path> (or: /session save <id> <path>)", nil
+		}
+		if !rt.EnableFS {
+			return "filesystem disabled (set AGENT_ENABLE_FS=true)", nil
+		}
+		id, path := "", rest[0]
+		if len(rest) > 1 {
+			id, path = rest[0], rest[1]
+		}
+		abs, err := (security.SafeFS{AllowDirs: rt.AllowDirs}).ResolveForWrite(path)
+		if err != nil {
+			return err.Error(), nil
+		}
+		if err := rt.SessionSave(id, abs); err != nil {
+			return err.Error(), nil
+		}
+		return fmt.Sprintf("saved session to %s", abs), nil
+
+	case "replay":
+		if rt.SessionReplay == nil {
+			return "sessions unavailable", nil
+		}
+		if len(rest) == 0 {
+			return "usage: /session replay <id>", nil
+		}
+		out, err := rt.SessionReplay(rest[0])
+		if err != nil {
+			return err.Error(), nil
+		}
+		return out, nil
+
+	case "fork":
+		if rt.SessionFork == nil {
+			return "sessions unavailable", nil
+		}
+		info := rt.SessionFork()
+		return fmt.Sprintf("forked into session: %s", info.ID), nil
+
+	default:
+		return "usage: " + t.Help(), nil
+	}
+}
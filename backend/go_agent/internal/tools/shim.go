@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// CommandShim translates a canonical POSIX-ish command line into something
+// the target shell can actually run -- e.g. mapping a Unix utility to its
+// PowerShell equivalent on Windows, or rewriting a heredoc into a `-c`
+// invocation a non-interactive shell can parse. RunTool runs every
+// registered shim over a command before executing it, each seeing the
+// previous one's output, so shims compose instead of fighting each other.
+type CommandShim interface {
+	// Name identifies the shim in "/run --dry-run" output.
+	Name() string
+	// Translate rewrites cmdLine, or returns it unchanged if it doesn't apply.
+	Translate(cmdLine string) string
+}
+
+var (
+	shimsMu sync.Mutex
+	shims   = platformShims()
+)
+
+// RegisterShim adds a user-defined CommandShim to the chain normalizeCommand
+// applies to every "/run" invocation, after the built-in ones.
+func RegisterShim(s CommandShim) {
+	if s == nil {
+		return
+	}
+	shimsMu.Lock()
+	defer shimsMu.Unlock()
+	shims = append(shims, s)
+}
+
+// normalizeCommand runs cmdLine through every registered CommandShim in
+// order and returns the command RunTool should actually execute, along with
+// the Name() of every shim that actually changed it -- "/run --dry-run"
+// reports those so a user can tell which translation produced the result.
+func normalizeCommand(cmdLine string) (string, []string) {
+	shimsMu.Lock()
+	chain := append([]CommandShim(nil), shims...)
+	shimsMu.Unlock()
+	var applied []string
+	for _, s := range chain {
+		next := s.Translate(cmdLine)
+		if next != cmdLine {
+			applied = append(applied, s.Name())
+		}
+		cmdLine = next
+	}
+	return cmdLine, applied
+}
+
+// platformShims returns the shims RunTool applies by default: the heredoc
+// rewrite always (any target shell benefits from it), plus the Unix-utility
+// and path-separator translations only where they're needed, on Windows.
+func platformShims() []CommandShim {
+	if runtime.GOOS == "windows" {
+		return []CommandShim{&heredocShim{}, &windowsUtilShim{}, &pathSepShim{}}
+	}
+	return []CommandShim{&heredocShim{}}
+}
+
+// heredocShim rewrites a `<interp> <<TAG ... TAG` (or `<<-TAG`/`<<'TAG'`)
+// heredoc into a single `<interp> -c "..."` invocation, so python/node/bash
+// "scripts" an LLM writes as a heredoc still run under a shell (powershell,
+// cmd) that has no heredoc syntax of its own. The closing tag must match
+// the opener exactly, same as a real shell; Go's regexp has no
+// backreferences, so the match is done by hand rather than one regex.
+type heredocShim struct{}
+
+func (h *heredocShim) Name() string { return "heredoc" }
+
+var heredocHead = regexp.MustCompile(`^(\S+)\s+<<(-)?\s*(?:'([^']+)'|"([^"]+)"|(\w+))\s*$`)
+
+// interpreterDashC lists the interpreters this shim knows accept a `-c
+// <script>` flag. Any other command in front of a heredoc (cat, tee, sort,
+// ...) reads its own stdin instead, so rewriting those into "-c" would
+// change their meaning -- the shim leaves them untouched.
+var interpreterDashC = map[string]bool{
+	"python": true, "python3": true, "node": true,
+	"bash": true, "sh": true, "zsh": true, "ruby": true, "perl": true,
+}
+
+func (h *heredocShim) Translate(cmdLine string) string {
+	lines := strings.Split(cmdLine, "\n")
+	m := heredocHead.FindStringSubmatch(lines[0])
+	if m == nil || !interpreterDashC[m[1]] {
+		return cmdLine
+	}
+	interp := m[1]
+	dashed := m[2] == "-"
+	tag := m[3]
+	if tag == "" {
+		tag = m[4]
+	}
+	if tag == "" {
+		tag = m[5]
+	}
+
+	end := -1
+	for i := 1; i < len(lines); i++ {
+		l := lines[i]
+		if dashed {
+			l = strings.TrimLeft(l, "\t")
+		}
+		if l == tag {
+			end = i
+			break
+		}
+	}
+	if end == -1 {
+		// No closing tag in this buffer -- leave it alone rather than guess.
+		return cmdLine
+	}
+
+	body := lines[1:end]
+	if dashed {
+		// Real `<<-` heredocs strip leading tabs from every body line, not
+		// just the one being matched against the closing tag.
+		for i, l := range body {
+			body[i] = strings.TrimLeft(l, "\t")
+		}
+	}
+	translated := interp + " -c " + shellQuote(strings.Join(body, "\n"))
+	if rest := strings.Join(lines[end+1:], "\n"); strings.TrimSpace(rest) != "" {
+		translated += "\n" + rest
+	}
+	return translated
+}
+
+// shellQuote wraps s in double quotes for use as a single argument, escaping
+// the characters that would otherwise end the quoted string or trigger
+// expansion inside it.
+func shellQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", "\\$", "`", "\\`")
+	return `"` + r.Replace(s) + `"`
+}
+
+// unixToPowerShell maps common Unix utility basenames to their closest
+// PowerShell cmdlet, for commands an LLM wrote assuming a POSIX shell.
+// Several of these (ls, cat, rm, cp, mv, pwd, echo) already work as built-in
+// PowerShell aliases, but are listed explicitly so the translation does not
+// rely on that alias staying in place.
+var unixToPowerShell = map[string]string{
+	"ls":    "Get-ChildItem",
+	"rm":    "Remove-Item",
+	"cat":   "Get-Content",
+	"cp":    "Copy-Item",
+	"mv":    "Move-Item",
+	"mkdir": "New-Item -ItemType Directory -Force",
+	"rmdir": "Remove-Item -Recurse -Force",
+	"touch": "New-Item -ItemType File -Force",
+	"pwd":   "Get-Location",
+	"which": "Get-Command",
+	"grep":  "Select-String",
+	"echo":  "Write-Output",
+}
+
+// windowsUtilShim rewrites a command's leading token from a Unix utility
+// name to its PowerShell equivalent via unixToPowerShell, leaving the rest
+// of the line (its arguments) untouched.
+type windowsUtilShim struct{}
+
+func (w *windowsUtilShim) Name() string { return "windows-util" }
+
+func (w *windowsUtilShim) Translate(cmdLine string) string {
+	fields := strings.SplitN(strings.TrimSpace(cmdLine), " ", 2)
+	if len(fields) == 0 {
+		return cmdLine
+	}
+	repl, ok := unixToPowerShell[fields[0]]
+	if !ok {
+		return cmdLine
+	}
+	if len(fields) == 1 {
+		return repl
+	}
+	return repl + " " + fields[1]
+}
+
+// pathSepShim rewrites forward slashes to backslashes in path-looking
+// arguments (tokens containing a "/" that aren't flags), so a path an LLM
+// wrote POSIX-style still resolves under Windows. It leaves URLs alone,
+// since rewriting "https://" would break them.
+type pathSepShim struct{}
+
+func (p *pathSepShim) Name() string { return "path-separators" }
+
+func (p *pathSepShim) Translate(cmdLine string) string {
+	if strings.Contains(cmdLine, "\n") {
+		// A multi-line command is a quoted script body (e.g. heredocShim's
+		// "-c" output) rather than a flat arg list; splitting it on
+		// strings.Fields would collapse that body's own whitespace, so
+		// leave it alone instead of guessing which spaces are significant.
+		return cmdLine
+	}
+	fields := strings.Fields(cmdLine)
+	for i, f := range fields {
+		if i == 0 || strings.HasPrefix(f, "-") || strings.Contains(f, "://") || !strings.Contains(f, "/") {
+			continue
+		}
+		fields[i] = strings.ReplaceAll(f, "/", `\`)
+	}
+	return strings.Join(fields, " ")
+}
@@ -2,21 +2,53 @@ package tools
 
 import (
 	"context"
+	"time"
+
+	"yogz/go_agent/internal/jsonschema"
 )
 
+// SessionInfo is the plain-data view of a memory.Session that SessionTool
+// renders for "/session list/new/fork" -- Runtime's hooks return this
+// instead of a *memory.Session so this package doesn't need to import
+// memory, same as GetStatus returning map[string]any rather than a config
+// type.
+type SessionInfo struct {
+	ID        string
+	CreatedAt time.Time
+	Model     string
+	Turns     int
+}
+
 type Runtime struct {
-	GetStatus func() map[string]any
-	GetModel  func() string
-	SetModel  func(string)
+	GetStatus  func() map[string]any
+	GetModel   func() string
+	SetModel   func(string)
+	ListModels func() []string
 
-	EnableFS bool
-	AllowDirs []string
+	EnableFS      bool
+	AllowDirs     []string
+	MaxWriteBytes int64
+	RunPolicy     RunPolicy
 
 	PythonToolsURL string
+
+	// Session hooks back the "session" tool; all of them operate on the
+	// calling user's session set (see agent.Agent.NewToolEnv).
+	SessionNew    func() SessionInfo
+	SessionList   func() []SessionInfo
+	SessionLoad   func(id string) error
+	SessionSave   func(id, path string) error
+	SessionReplay func(id string) (string, error)
+	SessionFork   func() SessionInfo
 }
 
+// Tool is a single callable capability exposed to the CLI, the HTTP API and
+// the model's tool-calling loop. Schema describes its arguments so callers
+// that only see a manifest (e.g. the model, or /v1/tools/schema) know how to
+// invoke it without reading source.
 type Tool interface {
 	Name() string
 	Help() string
+	Schema() jsonschema.Schema
 	Run(ctx context.Context, rt *Runtime, args []string) (string, error)
 }
@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"yogz/go_agent/internal/jsonschema"
+	"yogz/go_agent/internal/security"
+)
+
+// defaultMaxWriteBytes is used when the Runtime wasn't given one, so the
+// tool still fails closed instead of accepting unbounded content.
+const defaultMaxWriteBytes = 1024 * 1024
+
+type WriteTool struct{}
+
+func (t *WriteTool) Name() string { return "write" }
+
+func (t *WriteTool) Help() string { return "/write <path> <content> - create or overwrite a file" }
+
+func (t *WriteTool) Schema() jsonschema.Schema {
+	return jsonschema.Object("Create or overwrite a file with the given content", map[string]jsonschema.Schema{
+		"path":    jsonschema.String("file to write, relative to an allowed root"),
+		"content": jsonschema.String("full contents to write"),
+	}, "path", "content")
+}
+
+func (t *WriteTool) Run(ctx context.Context, rt *Runtime, args []string) (string, error) {
+	_ = ctx
+	if rt == nil {
+		return "filesystem unavailable", nil
+	}
+	if !rt.EnableFS {
+		return "filesystem disabled (set AGENT_ENABLE_FS=true)", nil
+	}
+	if len(args) < 2 {
+		return "usage: /write <path> <content>", nil
+	}
+	path := strings.TrimSpace(args[0])
+	content := strings.Join(args[1:], " ")
+	if path == "" {
+		return "usage: /write <path> <content>", nil
+	}
+	maxBytes := rt.MaxWriteBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxWriteBytes
+	}
+	if int64(len(content)) > maxBytes {
+		return fmt.Sprintf("content too large: %d bytes exceeds the %d byte limit", len(content), maxBytes), nil
+	}
+	abs, err := (security.SafeFS{AllowDirs: rt.AllowDirs}).ResolveForWrite(path)
+	if err != nil {
+		return err.Error(), nil
+	}
+	if err := atomicWrite(abs, []byte(content)); err != nil {
+		return "write failed: " + err.Error(), nil
+	}
+	return fmt.Sprintf("wrote %d bytes to %s", len(content), abs), nil
+}
+
+// atomicWrite writes data to a temp file in dst's directory and renames it
+// into place, so a crash or concurrent read never sees a partially written
+// file at dst.
+func atomicWrite(dst string, data []byte) error {
+	dir := filepath.Dir(dst)
+	tmp, err := os.CreateTemp(dir, ".tmp-"+filepath.Base(dst)+"-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, dst); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"sync"
 	"time"
 )
 
@@ -34,6 +35,65 @@ func Spinner(msg string, fn func() (string, error)) (string, error) {
 	}
 }
 
+// StreamingSpinner behaves like Spinner, except fn is given an onToken
+// callback: the first call to onToken clears the spinner and switches to
+// printing deltas live, so a streaming answer shows up as it's generated
+// instead of only after fn returns. If onToken is never called (e.g. a
+// tool-calling turn with nothing to preview), it behaves exactly like
+// Spinner. onToken may be called from a different goroutine than the one
+// fn runs on; it's safe to call concurrently with itself.
+func StreamingSpinner(msg string, fn func(onToken func(string)) (string, error)) (string, error) {
+	spinChars := []string{"|", "/", "-", "\\"}
+	i := 0
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+
+	done := make(chan struct{})
+	var result string
+	var err error
+	var mu sync.Mutex
+	streaming := false
+
+	onToken := func(delta string) {
+		if delta == "" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if !streaming {
+			streaming = true
+			ClearLine()
+		}
+		fmt.Print(delta)
+	}
+
+	go func() {
+		result, err = fn(onToken)
+		close(done)
+	}()
+
+	for {
+		select {
+		case <-done:
+			mu.Lock()
+			if streaming {
+				fmt.Println()
+			} else {
+				fmt.Print("\r")
+			}
+			mu.Unlock()
+			return result, err
+		case <-ticker.C:
+			mu.Lock()
+			if !streaming {
+				fmt.Printf("\r%s %s", spinChars[i%len(spinChars)], msg)
+				i++
+			}
+			mu.Unlock()
+		}
+	}
+}
+
 // Badge prints a styled badge prefix for command outputs.
 func Badge(label, color string) string {
 	// Simple ANSI color codes